@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	serverless_client "github.com/transparency-dev/serverless-log/client"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/klog/v2"
+)
+
+// tileMismatch is a published hash tile that differs from the tile
+// recomputed from the log's leaves.
+type tileMismatch struct {
+	level, index, width uint64
+	path                string
+	// err is set if the published tile couldn't be parsed. Otherwise,
+	// position is the first hash in the tile's bottom row that differs,
+	// which is got rather than want, and differing is how many differ.
+	err       error
+	position  uint64
+	differing int
+	got, want []byte
+}
+
+func (t tileMismatch) String() string {
+	if t.err != nil {
+		return fmt.Sprintf("tile level %d index %d width %d (%s): %v", t.level, t.index, t.width, t.path, t.err)
+	}
+	return fmt.Sprintf("tile level %d index %d width %d (%s): %d hashes differ, first at position %d (node %d at tree level %d): published %x, recomputed %x",
+		t.level, t.index, t.width, t.path, t.differing, t.position, t.index*256+t.position, 8*t.level, t.got, t.want)
+}
+
+// auditReport is the result of auditing a log.
+type auditReport struct {
+	checkpoint *log.Checkpoint
+	// root is the root hash recomputed from every leaf.
+	root       []byte
+	tiles      int
+	mismatches []tileMismatch
+}
+
+// ok returns whether every published tile, and the checkpoint, matched the
+// log's leaves.
+func (r *auditReport) ok() bool {
+	return len(r.mismatches) == 0 && bytes.Equal(r.root, r.checkpoint.Hash)
+}
+
+// auditor recomputes a log's hash tiles from its leaves.
+type auditor struct {
+	fetch    serverless_client.Fetcher
+	layout   *tileLayout
+	size     uint64
+	progress io.Writer
+	report   auditReport
+	tree     *compact.Range
+	// rows holds the bottom row of the tile being built at each level
+	// above 0, and done counts the full tiles built at each level.
+	rows [][][]byte
+	done []uint64
+}
+
+// auditLog downloads every leaf of the log up to its checkpoint, hashes
+// them, and rebuilds every hash tile, comparing each against the published
+// tile and the root hash against the checkpoint. Unlike mirroring, which
+// checks the tiles against each other, this catches published tiles that
+// are consistent with the checkpoint but not with the leaves. Progress is
+// written to progress.
+func auditLog(ctx context.Context, client logClient, progress io.Writer) (*auditReport, error) {
+	layout, err := layoutFor(client.GetLogType())
+	if err != nil {
+		return nil, err
+	}
+	root, err := url.Parse(client.GetURL())
+	if err != nil {
+		return nil, err
+	}
+	fetch, err := newLogFetcher(client.GetOrigin(), root)
+	if err != nil {
+		return nil, err
+	}
+	cpRaw, err := fetch(ctx, layout.checkpointPath)
+	if err != nil {
+		return nil, fmt.Errorf("fetching checkpoint: %w", err)
+	}
+	cp, _, _, err := log.ParseCheckpoint(cpRaw, client.GetOrigin(), client.GetVerifier())
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	a := &auditor{fetch: fetch, layout: layout, size: cp.Size, progress: progress, tree: rf.NewEmptyRange(0)}
+	a.report.checkpoint = cp
+	if err := a.run(ctx); err != nil {
+		return nil, err
+	}
+	return &a.report, nil
+}
+
+// auditedTile is a level 0 tile's leaf hashes, and its published tile.
+type auditedTile struct {
+	hashes    [][]byte
+	published []byte
+}
+
+func (a *auditor) run(ctx context.Context) error {
+	tiles := (a.size + 255) / 256
+	for start := uint64(0); start < tiles; start += mirrorConcurrency {
+		batch := make([]auditedTile, min(mirrorConcurrency, tiles-start))
+		g, gctx := errgroup.WithContext(ctx)
+		for i := range batch {
+			index := start + uint64(i)
+			g.Go(func() (err error) {
+				batch[i], err = a.fetchTile(gctx, index)
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+		for i, t := range batch {
+			index := start + uint64(i)
+			a.check(0, index, t.hashes, t.published)
+			for _, h := range t.hashes {
+				if err := a.tree.Append(h, nil); err != nil {
+					return err
+				}
+			}
+			if len(t.hashes) == 256 {
+				if err := a.push(ctx, 1, subtreeRoot(t.hashes)); err != nil {
+					return err
+				}
+			}
+		}
+		if a.progress != nil {
+			fmt.Fprintf(a.progress, "\rAudited %d/%d leaves", min((start+uint64(len(batch)))*256, a.size), a.size)
+		}
+	}
+	if a.progress != nil && a.size > 0 {
+		fmt.Fprintln(a.progress)
+	}
+
+	// The partial tiles on the right edge of the tree.
+	for level := 1; level < len(a.rows); level++ {
+		if row := a.rows[level]; len(row) > 0 {
+			if err := a.fetchAndCheck(ctx, uint64(level), a.done[level], row); err != nil {
+				return err
+			}
+		}
+	}
+	if a.size == 0 {
+		a.report.root = rfc6962.DefaultHasher.EmptyRoot()
+		return nil
+	}
+	var err error
+	a.report.root, err = a.tree.GetRootHash(nil)
+	return err
+}
+
+// fetchTile fetches the leaves under the level 0 tile at index, and the
+// published tile.
+func (a *auditor) fetchTile(ctx context.Context, index uint64) (auditedTile, error) {
+	width := min(256, a.size-index*256)
+	var t auditedTile
+	for _, p := range a.layout.leafPaths(index, width) {
+		b, err := a.fetch(ctx, p)
+		if err != nil {
+			return t, fmt.Errorf("fetching %s: %w", p, err)
+		}
+		_, hashes, err := a.layout.leaves(b)
+		if err != nil {
+			return t, fmt.Errorf("%s: %w", p, err)
+		}
+		t.hashes = append(t.hashes, hashes...)
+	}
+	if uint64(len(t.hashes)) != width {
+		return t, fmt.Errorf("leaves under tile %d: got %d leaves, want %d", index, len(t.hashes), width)
+	}
+	p := a.layout.tilePath(0, index, width)
+	b, err := a.fetch(ctx, p)
+	if err != nil {
+		return t, fmt.Errorf("fetching %s: %w", p, err)
+	}
+	t.published = b
+	return t, nil
+}
+
+// push adds a recomputed node to the tile being built at level, checking
+// the tile once it's full.
+func (a *auditor) push(ctx context.Context, level int, h []byte) error {
+	for len(a.rows) <= level {
+		a.rows = append(a.rows, nil)
+		a.done = append(a.done, 0)
+	}
+	a.rows[level] = append(a.rows[level], h)
+	if len(a.rows[level]) < 256 {
+		return nil
+	}
+	row := a.rows[level]
+	a.rows[level] = nil
+	if err := a.fetchAndCheck(ctx, uint64(level), a.done[level], row); err != nil {
+		return err
+	}
+	a.done[level]++
+	return a.push(ctx, level+1, subtreeRoot(row))
+}
+
+func (a *auditor) fetchAndCheck(ctx context.Context, level, index uint64, want [][]byte) error {
+	p := a.layout.tilePath(level, index, uint64(len(want)))
+	b, err := a.fetch(ctx, p)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", p, err)
+	}
+	a.check(level, index, want, b)
+	return nil
+}
+
+// check compares a published tile with the recomputed bottom row want.
+func (a *auditor) check(level, index uint64, want [][]byte, published []byte) {
+	a.report.tiles++
+	width := uint64(len(want))
+	m := tileMismatch{level: level, index: index, width: width, path: a.layout.tilePath(level, index, width)}
+	got, err := a.layout.tileHashes(published, width)
+	if err != nil {
+		m.err = err
+		a.report.mismatches = append(a.report.mismatches, m)
+		return
+	}
+	for i := range want {
+		if bytes.Equal(got[i], want[i]) {
+			continue
+		}
+		if m.differing == 0 {
+			m.position, m.got, m.want = uint64(i), got[i], want[i]
+		}
+		m.differing++
+	}
+	if m.differing > 0 {
+		a.report.mismatches = append(a.report.mismatches, m)
+	}
+}
+
+// subtreeRoot returns the root of the perfect subtree with the given
+// leaves.
+func subtreeRoot(hashes [][]byte) []byte {
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	cr := rf.NewEmptyRange(0)
+	for _, h := range hashes {
+		_ = cr.Append(h, nil)
+	}
+	root, _ := cr.GetRootHash(nil)
+	return root
+}
+
+// runAudit runs the audit command, which audits the log chosen with
+// --origin, or else the custom log or first built-in log.
+func runAudit(args []string) {
+	if len(args) != 0 {
+		klog.Exitf("Usage: woodpecker [flags] audit")
+	}
+	client := commandClient()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	r, err := auditLog(ctx, client, os.Stderr)
+	if err != nil {
+		klog.Exitf("Failed to audit %q: %v", client.GetOrigin(), err)
+	}
+	for _, m := range r.mismatches {
+		fmt.Println(m)
+	}
+	if !bytes.Equal(r.root, r.checkpoint.Hash) {
+		fmt.Printf("checkpoint: root hash is %x, recomputed %x\n", r.checkpoint.Hash, r.root)
+	}
+	if !r.ok() {
+		klog.Exitf("Audit of %q at size %d failed: %d of %d tiles differ from the leaves", client.GetOrigin(), r.checkpoint.Size, len(r.mismatches), r.tiles)
+	}
+	fmt.Printf("Audited %q at size %d: the %d hash tiles and the checkpoint root hash %x match the leaves\n", client.GetOrigin(), r.checkpoint.Size, r.tiles, r.root)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	const origin = "example.com/audited"
+	for _, tc := range []struct {
+		logType string
+		suffix  string
+		open    func(lr, origin, vkey string) (logClient, error)
+	}{
+		{logType: "tiles", open: newTLogTilesLogClient},
+		{logType: "sumdb", suffix: "\n", open: newSumDBLogClient},
+	} {
+		t.Run(tc.logType, func(t *testing.T) {
+			entries := testEntries(256*256+300, tc.suffix)
+			files, vkey := newTestLog(t, tc.logType, origin, entries)
+			client, err := tc.open(newTestLogServer(t, files).URL, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var progress bytes.Buffer
+			r, err := auditLog(t.Context(), client, &progress)
+			if err != nil {
+				t.Fatalf("auditLog: %v", err)
+			}
+			// 258 level 0 tiles, 2 at level 1 and 1 at level 2.
+			if !r.ok() || r.tiles != 261 {
+				t.Errorf("audit found %v in %d tiles, want 261 matching tiles", r.mismatches, r.tiles)
+			}
+			if !strings.Contains(progress.String(), "Audited 65836/65836 leaves") {
+				t.Errorf("progress %q doesn't report every leaf", progress.String()[max(0, progress.Len()-100):])
+			}
+		})
+	}
+}
+
+func TestAuditFindsMismatches(t *testing.T) {
+	const origin = "example.com/inconsistent"
+	entries := testEntries(256*256+300, "")
+	layout, _ := layoutFor("tiles")
+	for _, tc := range []struct {
+		name    string
+		tamper  func(files map[string][]byte)
+		want    []string
+		rootBad bool
+	}{
+		{
+			name: "level 0 tile",
+			tamper: func(files map[string][]byte) {
+				files[layout.tilePath(0, 3, 256)][40] ^= 1
+			},
+			want: []string{"tile level 0 index 3 width 256 (tile/0/003): 1 hashes differ, first at position 1 (node 769 at tree level 0)"},
+		},
+		{
+			name: "partial level 1 tile",
+			tamper: func(files map[string][]byte) {
+				files[layout.tilePath(1, 1, 1)] = []byte("short")
+			},
+			want: []string{"tile level 1 index 1 width 1 (tile/1/001.p/1): hash tile has 5 bytes, want 1 hashes"},
+		},
+		{
+			// A log serving a different leaf, with a checkpoint and tiles
+			// that are consistent with each other but not with the leaf.
+			name: "entry bundle",
+			tamper: func(files map[string][]byte) {
+				p := layout.leafPaths(5, 256)[0]
+				files[p] = bytes.Replace(files[p], []byte("entry 1300"), []byte("entry X300"), 1)
+			},
+			want:    []string{"tile level 0 index 5 width 256", "tile level 1 index 0 width 256 (tile/1/000): 1 hashes differ, first at position 5", "tile level 2 index 0 width 1"},
+			rootBad: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			files, vkey := newTestLog(t, "tiles", origin, entries)
+			tc.tamper(files)
+			client, err := newTLogTilesLogClient(newTestLogServer(t, files).URL, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := auditLog(t.Context(), client, nil)
+			if err != nil {
+				t.Fatalf("auditLog: %v", err)
+			}
+			if r.ok() {
+				t.Fatal("audit of an inconsistent log passed")
+			}
+			if len(r.mismatches) != len(tc.want) {
+				t.Fatalf("audit found %v, want %d mismatches", r.mismatches, len(tc.want))
+			}
+			for i, want := range tc.want {
+				if got := r.mismatches[i].String(); !strings.HasPrefix(got, want) {
+					t.Errorf("mismatch %d = %q, want prefix %q", i, got, want)
+				}
+			}
+			if rootBad := !bytes.Equal(r.root, r.checkpoint.Hash); rootBad != tc.rootBad {
+				t.Errorf("recomputed root differs from checkpoint: %v, want %v", rootBad, tc.rootBad)
+			}
+		})
+	}
+}
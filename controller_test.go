@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/rand"
+	"errors"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,8 +17,8 @@ type mockLogClient struct{}
 func (m *mockLogClient) GetOrigin() string                         { return "origin" }
 func (m *mockLogClient) GetVerifier() note.Verifier                { return nil }
 func (m *mockLogClient) GetCheckpoint() (*model.Checkpoint, error) { return nil, nil }
-func (m *mockLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) ([]byte, error) {
-	return []byte("leaf"), nil
+func (m *mockLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error) {
+	return &model.Leaf{Contents: []byte("leaf"), Index: index}, nil
 }
 func (m *mockLogClient) FormatLeaf(leaf []byte) string {
 	return string(leaf)
@@ -27,7 +30,7 @@ func TestPrevLeafUnderflow(t *testing.T) {
 	clients := map[string]logClient{
 		"origin": &mockLogClient{},
 	}
-	m := NewModel([]string{"origin"}, clients, &mockDistributor{}, nil, "origin")
+	m := NewModel([]string{"origin"}, clients, mockDistributors(&mockDistributor{}), nil, "origin", modelOptions{})
 
 	// Set up model state
 	m.checkpoint = &model.Checkpoint{
@@ -57,7 +60,7 @@ func TestNextLeafOverflow(t *testing.T) {
 	clients := map[string]logClient{
 		"origin": &mockLogClient{},
 	}
-	m := NewModel([]string{"origin"}, clients, &mockDistributor{}, nil, "origin")
+	m := NewModel([]string{"origin"}, clients, mockDistributors(&mockDistributor{}), nil, "origin", modelOptions{})
 
 	// Set up model state: last leaf is at index 9 for size 10
 	m.checkpoint = &model.Checkpoint{
@@ -82,3 +85,222 @@ func TestNextLeafOverflow(t *testing.T) {
 		t.Errorf("Expected no command to be returned on overflow, but got one")
 	}
 }
+
+func TestLeafVerificationStatus(t *testing.T) {
+	clients := map[string]logClient{
+		"origin": &mockLogClient{},
+	}
+	m := NewModel([]string{"origin"}, clients, mockDistributors(&mockDistributor{}), nil, "origin", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m.loadingCheck = false
+	cp := &model.Checkpoint{Checkpoint: &log.Checkpoint{Size: 10}}
+	m.checkpoint = cp
+
+	// A verified leaf shows the tree size it was proven against.
+	m.Update(leafMsg{
+		leaf: model.Leaf{
+			Index:    4,
+			Contents: []byte("verified contents"),
+			Proof:    &model.InclusionProof{Index: 4, TreeSize: 10},
+			Verified: true,
+		},
+		checkpoint: cp,
+	})
+	if view := m.View(); !strings.Contains(view, "Verified in tree size 10") {
+		t.Errorf("expected verified badge in view, got:\n%s", view)
+	}
+
+	// A leaf which failed verification keeps its contents in the viewport
+	// and shows the error separately.
+	m.Update(leafMsg{
+		leaf: model.Leaf{
+			Index:    5,
+			Contents: []byte("unverified contents"),
+		},
+		checkpoint: cp,
+		err:        errors.New("failed to verify inclusion proof: bad root"),
+	})
+	view := m.View()
+	if !strings.Contains(view, "Unverified") || !strings.Contains(view, "bad root") {
+		t.Errorf("expected unverified badge and error in view, got:\n%s", view)
+	}
+	if vp := m.viewport.View(); !strings.Contains(vp, "unverified contents") || strings.Contains(vp, "bad root") {
+		t.Errorf("expected viewport to hold only the leaf contents, got:\n%s", vp)
+	}
+	if m.activeErr != nil {
+		t.Errorf("expected leaf errors not to be reported as checkpoint errors, got %v", m.activeErr)
+	}
+}
+
+// provingLogClient records the checkpoint that each leaf is proven against.
+type provingLogClient struct {
+	mockLogClient
+	provenSizes []uint64
+}
+
+func (c *provingLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error) {
+	c.provenSizes = append(c.provenSizes, checkpoint.Size)
+	return &model.Leaf{
+		Contents: []byte("leaf"),
+		Index:    index,
+		Proof:    &model.InclusionProof{Index: index, TreeSize: checkpoint.Size},
+		Verified: true,
+	}, nil
+}
+
+func TestProveAgainstWitnessed(t *testing.T) {
+	client := &provingLogClient{}
+	clients := map[string]logClient{
+		"origin": client,
+	}
+	m := NewModel([]string{"origin"}, clients, mockDistributors(&mockDistributor{}), nil, "origin", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m.loadingCheck = false
+	m.checkpoint = &model.Checkpoint{Checkpoint: &log.Checkpoint{Size: 20}}
+	m.witnessed = &model.Checkpoint{Checkpoint: &log.Checkpoint{Size: 10}, Note: &note.Note{}}
+	m.leaf = model.Leaf{Index: 5, Contents: []byte("leaf")}
+
+	// Toggling the mode re-proves the current leaf against the witnessed checkpoint.
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'v'}})
+	if !m.proveWitnessed {
+		t.Fatal("expected witnessed proving mode to be enabled")
+	}
+	if got := client.provenSizes; len(got) != 1 || got[0] != 10 {
+		t.Fatalf("expected leaf 5 to be proven against size 10, got %v", got)
+	}
+	if !m.leafWitnessed || m.leafNotWitnessed {
+		t.Errorf("expected leaf 5 to be witnessed, got witnessed=%t notWitnessed=%t", m.leafWitnessed, m.leafNotWitnessed)
+	}
+	if view := m.View(); !strings.Contains(view, "Verified in witnessed tree size 10") {
+		t.Errorf("expected witnessed badge in view, got:\n%s", view)
+	}
+
+	// Leaves beyond the witnessed size are proven against the log checkpoint
+	// and flagged as not yet witnessed.
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'g'}})
+	sendString(t, m, "15")
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if got := client.provenSizes; len(got) != 2 || got[1] != 20 {
+		t.Fatalf("expected leaf 15 to be proven against size 20, got %v", got)
+	}
+	if m.leafWitnessed || !m.leafNotWitnessed {
+		t.Errorf("expected leaf 15 not to be witnessed, got witnessed=%t notWitnessed=%t", m.leafWitnessed, m.leafNotWitnessed)
+	}
+	if view := m.View(); !strings.Contains(view, "Not yet witnessed") {
+		t.Errorf("expected not yet witnessed badge in view, got:\n%s", view)
+	}
+
+	// Once the witnessed checkpoint catches up, the leaf is re-proven.
+	_, cmd := m.Update(checkpointMsg{
+		origin:     m.currentLog,
+		checkpoint: m.checkpoint,
+		witnessed:  &model.Checkpoint{Checkpoint: &log.Checkpoint{Size: 20}, Note: &note.Note{}},
+	})
+	processCmds(t, m, cmd)
+	if got := client.provenSizes; len(got) != 3 || got[2] != 20 {
+		t.Errorf("expected leaf 15 to be re-proven against the witnessed size 20, got %v", got)
+	}
+	if !m.leafWitnessed {
+		t.Error("expected leaf 15 to be witnessed once the witnessed checkpoint grew")
+	}
+}
+
+func TestWithoutDistributor(t *testing.T) {
+	clients := map[string]logClient{
+		"origin": &mockLogClient{},
+	}
+	m := NewModel([]string{"origin"}, clients, nil, nil, "origin", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	msg, ok := m.fetchCheckpointCmd()().(checkpointMsg)
+	if !ok {
+		t.Fatal("expected a checkpointMsg")
+	}
+	if msg.witnessed != nil {
+		t.Errorf("expected no witnessed checkpoint without a distributor, got %v", msg.witnessed)
+	}
+	m.Update(msg)
+	if view := m.View(); !strings.Contains(view, "Witnessed checkpoints are disabled") {
+		t.Errorf("expected witnessed panel to be disabled, got:\n%s", view)
+	}
+}
+
+func TestDistributorUnavailable(t *testing.T) {
+	clients := map[string]logClient{
+		"origin": &mockLogClient{},
+	}
+	dist := &mockDistributor{witnessesErr: errors.New("connection refused")}
+	m := NewModel([]string{"origin"}, clients, mockDistributors(dist), nil, "origin", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m.loadingCheck = false
+
+	m.Update(m.fetchWitnessesCmd()())
+	if view := m.View(); !strings.Contains(view, "Distributor unavailable") {
+		t.Errorf("expected witnessed panel to show the distributor as unavailable, got:\n%s", view)
+	}
+	if m.witVerifiers != nil {
+		t.Errorf("expected no witness verifiers, got %v", m.witVerifiers)
+	}
+
+	// The retry picks up the witnesses once the distributor is back.
+	_, vkey, err := note.GenerateKey(rand.Reader, "witness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dist.witnessesErr = nil
+	dist.witnesses = []string{vkey}
+	_, cmd := m.Update(retryWitnessesMsg{})
+	if cmd == nil {
+		t.Fatal("expected a command to retry fetching witnesses")
+	}
+	m.Update(cmd())
+	if m.witnessesErr != nil {
+		t.Errorf("expected the error to be cleared, got %v", m.witnessesErr)
+	}
+	if got := len(m.witVerifiers); got != 1 {
+		t.Errorf("expected 1 witness verifier, got %d", got)
+	}
+}
+
+// namedLogClient is a log of the given size with its own origin.
+type namedLogClient struct {
+	mockLogClient
+	origin string
+	size   uint64
+}
+
+func (c *namedLogClient) GetOrigin() string { return c.origin }
+func (c *namedLogClient) GetCheckpoint() (*model.Checkpoint, error) {
+	return &model.Checkpoint{Checkpoint: &log.Checkpoint{Origin: c.origin, Size: c.size}}, nil
+}
+
+func TestSwitchLogsWhileFetching(t *testing.T) {
+	clients := map[string]logClient{
+		"a": &namedLogClient{origin: "a", size: 100},
+		"b": &namedLogClient{origin: "b", size: 5},
+	}
+	m := NewModel([]string{"a", "b"}, clients, nil, nil, "a", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	// The checkpoint of log a is still being fetched when b is selected.
+	pending := m.fetchCheckpointCmd()
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.currentLog != "b" {
+		t.Fatalf("currentLog = %q, want b", m.currentLog)
+	}
+
+	m.Update(pending())
+	if m.checkpoint != nil || !m.loadingCheck {
+		t.Errorf("checkpoint of log a was taken for log b: %v, loading %v", m.checkpoint, m.loadingCheck)
+	}
+	if got := m.logHistory("b").entries; len(got) != 0 {
+		t.Errorf("history of log b = %v, want it empty", got)
+	}
+
+	m.Update(m.fetchCheckpointCmd()())
+	if m.checkpoint == nil || m.checkpoint.Size != 5 {
+		t.Errorf("checkpoint = %v, want log b's of size 5", m.checkpoint)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	tnote "github.com/transparency-dev/formats/note"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// defaultStaleWitnessThreshold is the cosignature age beyond which a
+// witness is highlighted as stale, unless overridden by a flag.
+const defaultStaleWitnessThreshold = time.Hour
+
+// cosignature is a witness cosignature on a checkpoint, with the time at
+// which the witness made it.
+type cosignature struct {
+	name      string
+	timestamp time.Time
+	err       error
+}
+
+// cosignatures decodes the timestamps of the verified witness cosignatures
+// on n, ordered from the freshest. The log's own signature is skipped.
+func cosignatures(n *note.Note) []cosignature {
+	var cs []cosignature
+	for i, s := range n.Sigs {
+		if i == 0 {
+			continue
+		}
+		ts, err := tnote.CoSigV1Timestamp(s)
+		cs = append(cs, cosignature{name: s.Name, timestamp: ts, err: err})
+	}
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].timestamp.After(cs[j].timestamp) })
+	return cs
+}
+
+// formatAge returns a short human readable form of d, e.g. "3m" or "2d".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "in the future"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// cosignatureLine returns a plain text summary of c for the witnessed
+// panel, marking it if it is older than stale.
+func cosignatureLine(c cosignature, now time.Time, stale time.Duration) string {
+	if c.err != nil {
+		return fmt.Sprintf(" • %s (no timestamp)", c.name)
+	}
+	age := now.Sub(c.timestamp)
+	line := fmt.Sprintf(" • %s %s ago", c.name, formatAge(age))
+	if age > stale {
+		line += " ⚠ stale"
+	}
+	return line
+}
+
+// renderCosignatures draws a table of the cosignatures on n, with stale
+// ones highlighted.
+func renderCosignatures(n *note.Note, now time.Time, stale time.Duration) string {
+	staleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171"))
+	var sb strings.Builder
+	for _, c := range cosignatures(n) {
+		if c.err != nil {
+			fmt.Fprintf(&sb, " %-32s %v\n", c.name, c.err)
+			continue
+		}
+		age := now.Sub(c.timestamp)
+		line := fmt.Sprintf(" %-32s %-22s %s ago", c.name, c.timestamp.UTC().Format(time.DateTime), formatAge(age))
+		if age > stale {
+			line = staleStyle.Render(line + "  stale")
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
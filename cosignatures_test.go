@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tnote "github.com/transparency-dev/formats/note"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// cosignAt returns a cosignature/v1 signature line by the named witness over
+// text, made at the given time.
+func cosignAt(t *testing.T, name string, priv ed25519.PrivateKey, text string, ts time.Time) (note.Verifier, string) {
+	t.Helper()
+	pub := priv.Public().(ed25519.PublicKey)
+	v, err := tnote.NewVerifierForCosignatureV1(cosignatureV1VKey(name, pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := fmt.Sprintf("cosignature/v1\ntime %d\n%s", ts.Unix(), text)
+	sig := make([]byte, 4, 4+8+ed25519.SignatureSize)
+	binary.BigEndian.PutUint32(sig, v.KeyHash())
+	sig = binary.BigEndian.AppendUint64(sig, uint64(ts.Unix()))
+	sig = append(sig, ed25519.Sign(priv, []byte(msg))...)
+	return v, fmt.Sprintf("— %s %s\n", name, base64.StdEncoding.EncodeToString(sig))
+}
+
+func TestCosignatureFreshness(t *testing.T) {
+	const origin = "example.com/log"
+	logSKey, logVKey, err := note.GenerateKey(rand.Reader, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logSigner, err := note.NewSigner(logSKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logVerifier, err := note.NewVerifier(logVKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := origin + "\n10\nAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n"
+	signed, err := note.Sign(&note.Note{Text: text}, logSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	verifiers := []note.Verifier{logVerifier}
+	for _, w := range []struct {
+		name string
+		age  time.Duration
+	}{
+		{"old", 3 * time.Hour},
+		{"fresh", 2 * time.Minute},
+		{"middle", 30 * time.Minute},
+	} {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, line := cosignAt(t, w.name, priv, text, now.Add(-w.age))
+		verifiers = append(verifiers, v)
+		signed = append(signed, line...)
+	}
+	n, err := note.Open(signed, note.VerifierList(verifiers...))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	cs := cosignatures(n)
+	var names []string
+	for _, c := range cs {
+		if c.err != nil {
+			t.Errorf("cosignature %s: %v", c.name, c.err)
+		}
+		names = append(names, c.name)
+	}
+	if got, want := strings.Join(names, ","), "fresh,middle,old"; got != want {
+		t.Errorf("got cosignatures in order %s, want %s", got, want)
+	}
+	if got, want := now.Sub(cs[0].timestamp), 2*time.Minute; got != want {
+		t.Errorf("got freshest age %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		c         cosignature
+		wantAge   string
+		wantStale bool
+	}{
+		{c: cs[0], wantAge: "2m ago"},
+		{c: cs[1], wantAge: "30m ago"},
+		{c: cs[2], wantAge: "3h ago", wantStale: true},
+	} {
+		line := cosignatureLine(tc.c, now, time.Hour)
+		if !strings.Contains(line, tc.wantAge) {
+			t.Errorf("%s: got %q, want age %q", tc.c.name, line, tc.wantAge)
+		}
+		if got := strings.Contains(line, "stale"); got != tc.wantStale {
+			t.Errorf("%s: got stale %t, want %t in %q", tc.c.name, got, tc.wantStale, line)
+		}
+	}
+}
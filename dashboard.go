@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// logHealth is what the dashboard knows about a log, accumulated over every
+// refresh since startup.
+type logHealth struct {
+	// firstSize and firstSeen are from the first checkpoint seen, and are
+	// used to compute the growth rate.
+	firstSize uint64
+	firstSeen time.Time
+	size      uint64
+	hash      []byte
+	// lastChange is when the checkpoint was last seen to change, or when it
+	// was first seen.
+	lastChange time.Time
+	// witnesses is the number of verified cosignatures on the latest
+	// witnessed checkpoint, and witnessedSize its size.
+	witnesses     int
+	witnessedSize uint64
+	err           error
+	seen          bool
+}
+
+// dashboardResult is the outcome of refreshing a single log.
+type dashboardResult struct {
+	origin        string
+	size          uint64
+	hash          []byte
+	witnesses     int
+	witnessedSize uint64
+	witnessedHash []byte
+	err           error
+	at            time.Time
+}
+
+// update records the result of a refresh of the log. A checkpoint smaller
+// than the last one seen is reported as an error, and otherwise ignored, so
+// that the growth rate is only ever computed from sizes that increased.
+func (h *logHealth) update(r dashboardResult) {
+	h.err = r.err
+	if r.err != nil {
+		return
+	}
+	if h.seen && r.size < h.size {
+		h.err = fmt.Errorf("checkpoint size went back from %d to %d", h.size, r.size)
+		return
+	}
+	if !h.seen {
+		h.seen = true
+		h.firstSize = r.size
+		h.firstSeen = r.at
+		h.lastChange = r.at
+	} else if r.size != h.size || !bytes.Equal(r.hash, h.hash) {
+		h.lastChange = r.at
+	}
+	h.size = r.size
+	h.hash = r.hash
+	h.witnesses = r.witnesses
+	h.witnessedSize = r.witnessedSize
+}
+
+// growthPerMinute returns the number of leaves added per minute since the
+// log was first seen.
+func (h *logHealth) growthPerMinute(now time.Time) float64 {
+	elapsed := now.Sub(h.firstSeen).Minutes()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(h.size-h.firstSize) / elapsed
+}
+
+// dashboardTarget is a log to refresh, with the witnesses and witness
+// count to look for its witnessed checkpoint with.
+type dashboardTarget struct {
+	client    logClient
+	witnessN  uint
+	verifiers []note.Verifier
+	policy    *witnessPolicy
+}
+
+// refreshDashboard fetches the latest checkpoint and witnessed checkpoint of
+// every log concurrently.
+func refreshDashboard(targets []dashboardTarget, ds []distributor) []dashboardResult {
+	results := make([]dashboardResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := dashboardResult{origin: t.client.GetOrigin()}
+			cp, err := t.client.GetCheckpoint()
+			r.at = time.Now()
+			if err != nil {
+				r.err = err
+				results[i] = r
+				return
+			}
+			r.size = cp.Size
+			r.hash = cp.Hash
+			if w := fetchWitnessed(ds, t.client, t.witnessN, t.verifiers, t.policy).witnessed; w != nil {
+				r.witnesses = len(w.Note.Sigs) - 1
+				r.witnessedSize = w.Size
+				r.witnessedHash = w.Hash
+			}
+			results[i] = r
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// renderDashboard draws a row for each log, highlighting the row under the
+// cursor.
+func renderDashboard(origins []string, health map[string]*logHealth, cursor int, now time.Time, width int) string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+	good := lipgloss.NewStyle().Foreground(lipgloss.Color("#34D399"))
+	bad := lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171"))
+
+	originWidth := width - 70
+	if originWidth < 16 {
+		originWidth = 16
+	}
+	var sb strings.Builder
+	sb.WriteString(dim.Render(fmt.Sprintf("%-*s %12s %10s %10s %12s  %s", originWidth, "Log", "Size", "Leaves/min", "Changed", "Witnessed", "Status")))
+	for i, o := range origins {
+		sb.WriteString("\n")
+		h := health[o]
+		name := limitText(o, originWidth, 1)
+		var line string
+		switch {
+		case h == nil || (!h.seen && h.err == nil):
+			line = fmt.Sprintf("%-*s %12s %10s %10s %12s  %s", originWidth, name, "-", "-", "-", "-", "Fetching...")
+		case !h.seen:
+			line = fmt.Sprintf("%-*s %12s %10s %10s %12s  %s", originWidth, name, "-", "-", "-", "-", bad.Render("✗ "+limitText(h.err.Error(), 40, 1)))
+		default:
+			witnessed := "-"
+			if h.witnesses > 0 {
+				witnessed = fmt.Sprintf("%d (%d)", h.witnessedSize, h.witnesses)
+			}
+			status := good.Render("✓ Verified")
+			if h.err != nil {
+				status = bad.Render("✗ " + limitText(h.err.Error(), 40, 1))
+			}
+			line = fmt.Sprintf("%-*s %12d %10.1f %10s %12s  %s", originWidth, name, h.size, h.growthPerMinute(now), formatAge(now.Sub(h.lastChange)), witnessed, status)
+		}
+		if i == cursor {
+			line = jsonCursorStyle.Render(line)
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mhutchinson/woodpecker/model"
+	"github.com/transparency-dev/formats/log"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// dashboardLogClient is a log client with a fixed checkpoint size.
+type dashboardLogClient struct {
+	mockLogClient
+	origin string
+	size   uint64
+	err    error
+}
+
+func (c *dashboardLogClient) GetOrigin() string { return c.origin }
+func (c *dashboardLogClient) GetCheckpoint() (*model.Checkpoint, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &model.Checkpoint{
+		Checkpoint: &log.Checkpoint{Origin: c.origin, Size: c.size, Hash: bytes.Repeat([]byte{1}, 32)},
+		Note:       &note.Note{},
+	}, nil
+}
+
+func TestLogHealth(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	hash := bytes.Repeat([]byte{1}, 32)
+	h := &logHealth{}
+
+	h.update(dashboardResult{size: 100, hash: hash, at: start})
+	h.update(dashboardResult{size: 100, hash: hash, at: start.Add(time.Minute)})
+	if !h.lastChange.Equal(start) {
+		t.Errorf("lastChange = %v, want %v as the checkpoint didn't change", h.lastChange, start)
+	}
+
+	h.update(dashboardResult{size: 400, hash: bytes.Repeat([]byte{2}, 32), at: start.Add(2 * time.Minute)})
+	if want := start.Add(2 * time.Minute); !h.lastChange.Equal(want) {
+		t.Errorf("lastChange = %v, want %v", h.lastChange, want)
+	}
+	if got, want := h.growthPerMinute(start.Add(3*time.Minute)), 100.0; got != want {
+		t.Errorf("growthPerMinute = %v, want %v", got, want)
+	}
+
+	// A failed refresh keeps the last known state.
+	h.update(dashboardResult{err: errors.New("boom"), at: start.Add(4 * time.Minute)})
+	if h.size != 400 || h.err == nil {
+		t.Errorf("after failed refresh got size %d, err %v; want 400 and an error", h.size, h.err)
+	}
+
+	// A checkpoint that shrank is an error, and doesn't affect the growth.
+	h.update(dashboardResult{size: 50, hash: hash, at: start.Add(5 * time.Minute)})
+	if h.err == nil || !strings.Contains(h.err.Error(), "went back") {
+		t.Errorf("after the size shrank got err %v, want an error", h.err)
+	}
+	if got, want := h.growthPerMinute(start.Add(6*time.Minute)), 50.0; h.size != 400 || got != want {
+		t.Errorf("after the size shrank got size %d, growthPerMinute %v; want 400 and %v", h.size, got, want)
+	}
+	h.update(dashboardResult{size: 400, hash: bytes.Repeat([]byte{2}, 32), at: start.Add(7 * time.Minute)})
+	if h.err != nil {
+		t.Errorf("after the size recovered got err %v, want none", h.err)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	clients := map[string]logClient{
+		"a": &dashboardLogClient{origin: "a", size: 10},
+		"b": &dashboardLogClient{origin: "b", size: 20},
+		"c": &dashboardLogClient{origin: "c", err: errors.New("log unreachable")},
+	}
+	m := NewModel([]string{"a", "b", "c"}, clients, nil, nil, "a", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	if m.activeView != "dashboard" {
+		t.Fatalf("activeView = %q, want dashboard", m.activeView)
+	}
+	view := m.dashboardView.View()
+	for _, want := range []string{"✓ Verified", "log unreachable"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("dashboard does not contain %q:\n%s", want, view)
+		}
+	}
+	if h := m.dashboard["b"]; h == nil || h.size != 20 {
+		t.Errorf("dashboard health for b = %+v, want size 20", h)
+	}
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.activeView != "leaf" {
+		t.Errorf("activeView = %q, want leaf", m.activeView)
+	}
+	if m.currentLog != "b" {
+		t.Errorf("currentLog = %q, want b", m.currentLog)
+	}
+}
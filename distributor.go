@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mhutchinson/woodpecker/model"
+	distclient "github.com/transparency-dev/distributor/client"
+	"github.com/transparency-dev/formats/log"
+	tnote "github.com/transparency-dev/formats/note"
+	"golang.org/x/mod/sumdb/note"
+	"k8s.io/klog/v2"
+)
+
+type distributorClient interface {
+	GetWitnesses() ([]string, error)
+	GetCheckpointN(l distclient.LogID, n uint) ([]byte, error)
+	GetCheckpointWitness(l distclient.LogID, w string) ([]byte, error)
+}
+
+// distributor is a distributor of witnessed checkpoints, along with the
+// name it is shown under.
+type distributor struct {
+	name   string
+	client distributorClient
+}
+
+// newDistributors returns clients for each of the comma separated
+// distributor base URLs.
+func newDistributors(urls string) ([]distributor, error) {
+	var ds []distributor
+	for _, u := range strings.Split(urls, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		pu, err := url.Parse(u)
+		if err != nil || pu.Host == "" {
+			return nil, fmt.Errorf("invalid distributor URL %q", u)
+		}
+		ds = append(ds, distributor{
+			name:   pu.Host,
+			client: distclient.NewRestDistributor(strings.TrimSuffix(u, "/"), httpClient),
+		})
+	}
+	return ds, nil
+}
+
+// distributorResult is what a single distributor returned for a request
+// for a witnessed checkpoint.
+type distributorResult struct {
+	name string
+	// size and hash are from the checkpoint returned, and cosigs is the
+	// number of cosignatures on it from known witnesses.
+	size   uint64
+	hash   []byte
+	cosigs int
+	// invalid holds the reasons for cosignatures that the source left out
+	// of the checkpoint.
+	invalid []error
+	err     error
+}
+
+// Modes for combining the pinned witnesses with those advertised by the
+// distributors, set by the --distributor_witnesses flag.
+const (
+	// witnessesUnion trusts both the pinned and the advertised witnesses.
+	witnessesUnion = "union"
+	// witnessesIntersect trusts only pinned witnesses that are also
+	// advertised.
+	witnessesIntersect = "intersect"
+	// witnessesIgnore trusts only the pinned witnesses, and doesn't ask the
+	// distributors for theirs.
+	witnessesIgnore = "ignore"
+)
+
+// combineWitnesses returns the witnesses to trust according to mode. It
+// also returns the names of advertised witnesses which are not pinned, and
+// of pinned witnesses which are not advertised. These are only reported if
+// any witnesses are pinned.
+func combineWitnesses(mode string, pinned, advertised []note.Verifier) (trusted []note.Verifier, unknown, missing []string) {
+	if len(pinned) > 0 {
+		for _, v := range advertised {
+			if !containsVerifier(pinned, v) {
+				unknown = append(unknown, v.Name())
+			}
+		}
+		for _, v := range pinned {
+			if !containsVerifier(advertised, v) {
+				missing = append(missing, v.Name())
+			}
+		}
+	}
+	switch mode {
+	case witnessesIntersect:
+		for _, v := range pinned {
+			if containsVerifier(advertised, v) {
+				trusted = append(trusted, v)
+			}
+		}
+	case witnessesIgnore:
+		trusted = append(trusted, pinned...)
+	default:
+		trusted = dedupeVerifiers(append(append([]note.Verifier{}, pinned...), advertised...))
+	}
+	if trusted == nil {
+		trusted = []note.Verifier{}
+	}
+	return trusted, unknown, missing
+}
+
+func containsVerifier(vs []note.Verifier, v note.Verifier) bool {
+	for _, o := range vs {
+		if o.Name() == v.Name() && o.KeyHash() == v.KeyHash() {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchWitnessVerifiers returns verifiers for the union of the witnesses
+// known to the distributors. An error is only returned if no distributor
+// could be reached.
+func fetchWitnessVerifiers(ds []distributor) ([]note.Verifier, error) {
+	var (
+		vs   []note.Verifier
+		errs []error
+	)
+	for _, d := range ds {
+		keys, err := d.client.GetWitnesses()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.name, err))
+			continue
+		}
+		for _, k := range keys {
+			v, err := tnote.NewVerifierForCosignatureV1(k)
+			if err != nil {
+				klog.Warningf("Ignoring invalid witness key %q from %s: %v", k, d.name, err)
+				continue
+			}
+			vs = append(vs, v)
+		}
+	}
+	if len(errs) == len(ds) {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		klog.Warningf("Failed to fetch witnesses: %v", err)
+	}
+	return dedupeVerifiers(vs), nil
+}
+
+// cosigningLogClient is a log which serves checkpoints with witness
+// cosignatures itself, as Sigsum logs do.
+type cosigningLogClient interface {
+	logClient
+	// GetCosignedCheckpoint returns the latest checkpoint as a note with
+	// the log's signature followed by the cosignatures, and errors for the
+	// cosignatures which were left out because they didn't verify.
+	GetCosignedCheckpoint() ([]byte, []error, error)
+}
+
+// fetchWitnessed asks every distributor in parallel for the latest
+// checkpoint of the log with at least witnessN cosignatures, and the log
+// itself if it is a cosigningLogClient. Cosignatures on
+// identical checkpoints from different distributors are merged, and the
+// largest checkpoint with at least witnessN verified cosignatures is
+// returned in the witnessed field of the message. Checkpoints of the same
+// size with different root hashes are reported as a split view.
+func fetchWitnessed(ds []distributor, client logClient, witnessN uint, verifiers []note.Verifier, policy *witnessPolicy) checkpointMsg {
+	origin := client.GetOrigin()
+	logID := distclient.LogID(log.ID(origin))
+
+	// Logs which serve their own cosigned checkpoints are asked too, after
+	// the distributors.
+	cosigning, _ := client.(cosigningLogClient)
+	sources := len(ds)
+	if cosigning != nil {
+		sources++
+	}
+	results := make([]distributorResult, sources)
+	notes := make([]*note.Note, sources)
+	record := func(i int, bs []byte, err error) {
+		if err != nil {
+			results[i].err = err
+			return
+		}
+		cp, _, n, err := log.ParseCheckpoint(bs, origin, client.GetVerifier(), verifiers...)
+		if err != nil {
+			results[i].err = err
+			return
+		}
+		if policy != nil {
+			policy.adoptCosignatures(n)
+		}
+		results[i].size = cp.Size
+		results[i].hash = cp.Hash
+		results[i].cosigs = len(n.Sigs) - 1
+		notes[i] = n
+	}
+	var wg sync.WaitGroup
+	for i, d := range ds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].name = d.name
+			bs, err := d.client.GetCheckpointN(logID, witnessN)
+			record(i, bs, err)
+		}()
+	}
+	if cosigning != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[len(ds)].name = "log"
+			bs, invalid, err := cosigning.GetCosignedCheckpoint()
+			results[len(ds)].invalid = invalid
+			record(len(ds), bs, err)
+		}()
+	}
+	wg.Wait()
+
+	msg := checkpointMsg{distributors: results}
+	merged := mergeCosignedNotes(notes)
+	for i, n := range merged {
+		for _, o := range merged[i+1:] {
+			if n.size == o.size && !bytes.Equal(n.hash, o.hash) {
+				msg.splitView = true
+			}
+		}
+	}
+	for _, n := range merged {
+		// Only the log signature and cosignatures from known witnesses
+		// are verified, so don't trust the distributors' counts.
+		if uint(len(n.note.Sigs)-1) < witnessN {
+			continue
+		}
+		raw := formatNote(n.note)
+		cp, _, vn, err := log.ParseCheckpoint(raw, origin, client.GetVerifier(), verifiers...)
+		if err != nil {
+			klog.Warningf("Failed to parse merged checkpoint: %v", err)
+			continue
+		}
+		var result *policyResult
+		if policy != nil {
+			policy.adoptCosignatures(vn)
+			result = policy.Evaluate(vn)
+		}
+		if msg.cosigned == nil || result == nil || result.satisfied {
+			msg.cosigned = vn
+			msg.policy = result
+		}
+		if result != nil && !result.satisfied {
+			// A smaller checkpoint may still satisfy the policy.
+			continue
+		}
+		msg.witnessed = &model.Checkpoint{
+			Checkpoint: cp,
+			Note:       vn,
+			Raw:        raw,
+		}
+		break
+	}
+	return msg
+}
+
+type mergedNote struct {
+	size uint64
+	hash []byte
+	note *note.Note
+}
+
+// mergeCosignedNotes combines the signatures of notes with identical text,
+// returning the distinct notes in decreasing order of checkpoint size.
+func mergeCosignedNotes(notes []*note.Note) []mergedNote {
+	byText := make(map[string]*mergedNote)
+	var merged []*mergedNote
+	for _, n := range notes {
+		if n == nil {
+			continue
+		}
+		m, ok := byText[n.Text]
+		if !ok {
+			cp := &log.Checkpoint{}
+			if _, err := cp.Unmarshal([]byte(n.Text)); err != nil {
+				continue
+			}
+			m = &mergedNote{size: cp.Size, hash: cp.Hash, note: &note.Note{Text: n.Text}}
+			byText[n.Text] = m
+			merged = append(merged, m)
+		}
+		for _, s := range n.Sigs {
+			if !hasSig(m.note.Sigs, s) {
+				m.note.Sigs = append(m.note.Sigs, s)
+			}
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].size > merged[j].size })
+	out := make([]mergedNote, len(merged))
+	for i, m := range merged {
+		out[i] = *m
+	}
+	return out
+}
+
+func hasSig(sigs []note.Signature, s note.Signature) bool {
+	for _, o := range sigs {
+		if o.Name == s.Name && o.Hash == s.Hash {
+			return true
+		}
+	}
+	return false
+}
+
+// formatNote returns the signed note encoding of n. Unlike note.Sign, this
+// reuses the existing signatures rather than signing.
+func formatNote(n *note.Note) []byte {
+	var b bytes.Buffer
+	b.WriteString(n.Text)
+	b.WriteString("\n")
+	for _, s := range n.Sigs {
+		fmt.Fprintf(&b, "— %s %s\n", s.Name, s.Base64)
+	}
+	return b.Bytes()
+}
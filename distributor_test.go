@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/transparency-dev/formats/log"
+	tnote "github.com/transparency-dev/formats/note"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func TestFetchWitnessedMergesDistributors(t *testing.T) {
+	const origin = "example.com/log"
+	logSKey, logVKey, err := note.GenerateKey(rand.Reader, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logSigner, err := note.NewSigner(logSKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logVerifier, err := note.NewVerifier(logVKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := newTestWitness(t, "a"), newTestWitness(t, "b")
+	var verifiers []note.Verifier
+	for _, w := range []testWitness{a, b} {
+		v, err := tnote.NewVerifierForCosignatureV1(w.vkey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifiers = append(verifiers, v)
+	}
+	client := &policyLogClient{origin: origin, verifier: logVerifier}
+
+	small := log.Checkpoint{Origin: origin, Size: 10, Hash: bytes.Repeat([]byte{1}, 32)}
+	big := log.Checkpoint{Origin: origin, Size: 20, Hash: bytes.Repeat([]byte{2}, 32)}
+	fork := log.Checkpoint{Origin: origin, Size: 20, Hash: bytes.Repeat([]byte{3}, 32)}
+
+	for _, tc := range []struct {
+		name      string
+		dists     []*mockDistributor
+		wantSize  uint64 // 0 for no witnessed checkpoint
+		wantSplit bool
+	}{
+		{
+			name: "cosignatures merged",
+			dists: []*mockDistributor{
+				{checkpoint: signCheckpoint(t, big, logSigner, a)},
+				{checkpoint: signCheckpoint(t, big, logSigner, b)},
+			},
+			wantSize: 20,
+		},
+		{
+			name: "largest with enough cosignatures",
+			dists: []*mockDistributor{
+				{checkpoint: signCheckpoint(t, small, logSigner, a, b)},
+				{checkpoint: signCheckpoint(t, big, logSigner, a)},
+			},
+			wantSize: 10,
+		},
+		{
+			name: "unreachable distributor",
+			dists: []*mockDistributor{
+				{checkpoint: signCheckpoint(t, small, logSigner, a, b)},
+				{err: errors.New("connection refused")},
+			},
+			wantSize: 10,
+		},
+		{
+			name: "split view",
+			dists: []*mockDistributor{
+				{checkpoint: signCheckpoint(t, big, logSigner, a, b)},
+				{checkpoint: signCheckpoint(t, fork, logSigner, a, b)},
+			},
+			wantSize:  20,
+			wantSplit: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var ds []distributor
+			for i, d := range tc.dists {
+				ds = append(ds, distributor{name: string(rune('x' + i)), client: d})
+			}
+			msg := fetchWitnessed(ds, client, 2, verifiers, nil)
+			if msg.splitView != tc.wantSplit {
+				t.Errorf("got splitView %t, want %t", msg.splitView, tc.wantSplit)
+			}
+			if len(msg.distributors) != len(ds) {
+				t.Fatalf("got %d distributor results, want %d", len(msg.distributors), len(ds))
+			}
+			if tc.wantSize == 0 {
+				if msg.witnessed != nil {
+					t.Errorf("got witnessed size %d, want none", msg.witnessed.Size)
+				}
+				return
+			}
+			if msg.witnessed == nil {
+				t.Fatal("got no witnessed checkpoint")
+			}
+			if msg.witnessed.Size != tc.wantSize {
+				t.Errorf("got witnessed size %d, want %d", msg.witnessed.Size, tc.wantSize)
+			}
+			if got := len(msg.witnessed.Note.Sigs) - 1; got < 2 {
+				t.Errorf("got %d verified cosignatures, want at least 2", got)
+			}
+		})
+	}
+}
+
+func TestDistributorResultsShown(t *testing.T) {
+	clients := map[string]logClient{
+		"origin": &mockLogClient{},
+	}
+	dists := []distributor{
+		{name: "one.example.com", client: &mockDistributor{}},
+		{name: "two.example.com", client: &mockDistributor{}},
+	}
+	m := NewModel([]string{"origin"}, clients, dists, nil, "origin", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m.Update(checkpointMsg{
+		origin: "origin",
+		distributors: []distributorResult{
+			{name: "one.example.com", size: 20, hash: []byte{1}, cosigs: 2},
+			{name: "two.example.com", err: errors.New("connection refused")},
+		},
+		splitView: true,
+	})
+	view := m.View()
+	for _, want := range []string{"1/2 distributors", "Split view"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view does not contain %q:\n%s", want, view)
+		}
+	}
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	view = m.View()
+	for _, want := range []string{"one.example.com: size 20", "two.example.com: connection refused"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("witnesses view does not contain %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestCombineWitnesses(t *testing.T) {
+	var vs []note.Verifier
+	for _, name := range []string{"a", "b", "c"} {
+		v, err := tnote.NewVerifierForCosignatureV1(newTestWitness(t, name).vkey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vs = append(vs, v)
+	}
+	a, b, c := vs[0], vs[1], vs[2]
+	pinned := []note.Verifier{a, b}
+	advertised := []note.Verifier{b, c}
+
+	names := func(vs []note.Verifier) string {
+		var ns []string
+		for _, v := range vs {
+			ns = append(ns, v.Name())
+		}
+		return strings.Join(ns, ",")
+	}
+	for _, tc := range []struct {
+		mode        string
+		pinned      []note.Verifier
+		wantTrusted string
+		wantUnknown string
+		wantMissing string
+	}{
+		{mode: witnessesUnion, pinned: pinned, wantTrusted: "a,b,c", wantUnknown: "c", wantMissing: "a"},
+		{mode: witnessesIntersect, pinned: pinned, wantTrusted: "b", wantUnknown: "c", wantMissing: "a"},
+		{mode: witnessesIgnore, pinned: pinned, wantTrusted: "a,b", wantUnknown: "c", wantMissing: "a"},
+		// Without pinned witnesses there is nothing to warn about.
+		{mode: witnessesUnion, wantTrusted: "b,c"},
+	} {
+		t.Run(tc.mode, func(t *testing.T) {
+			trusted, unknown, missing := combineWitnesses(tc.mode, tc.pinned, advertised)
+			if got := names(trusted); got != tc.wantTrusted {
+				t.Errorf("got trusted %s, want %s", got, tc.wantTrusted)
+			}
+			if got := strings.Join(unknown, ","); got != tc.wantUnknown {
+				t.Errorf("got unknown %s, want %s", got, tc.wantUnknown)
+			}
+			if got := strings.Join(missing, ","); got != tc.wantMissing {
+				t.Errorf("got missing %s, want %s", got, tc.wantMissing)
+			}
+		})
+	}
+}
+
+func TestPinnedWitnessWarnings(t *testing.T) {
+	pinned := newTestWitness(t, "pinned")
+	advertised := newTestWitness(t, "advertised")
+	pv, err := tnote.NewVerifierForCosignatureV1(pinned.vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := map[string]logClient{
+		"origin": &mockLogClient{},
+	}
+	dist := &mockDistributor{witnesses: []string{advertised.vkey}}
+	m := NewModel([]string{"origin"}, clients, mockDistributors(dist), nil, "origin", modelOptions{
+		pinnedWitnesses: []note.Verifier{pv},
+		witnessMode:     witnessesIntersect,
+	})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	m.Update(m.fetchWitnessesCmd()())
+	if len(m.witVerifiers) != 0 {
+		t.Errorf("got %d trusted witnesses, want none in the intersection", len(m.witVerifiers))
+	}
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	view := m.View()
+	for _, want := range []string{"not pinned: advertised", "not advertised by distributors: pinned"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("witnesses view does not contain %q:\n%s", want, view)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mhutchinson/woodpecker/model"
+	"k8s.io/klog/v2"
+)
+
+// Formats that leaves can be exported in.
+const (
+	exportJSONL = "jsonl"
+	exportCSV   = "csv"
+	exportFiles = "files"
+)
+
+// structuredLogClient is implemented by log clients whose leaves have named
+// fields, which can be exported as CSV.
+type structuredLogClient interface {
+	LeafFields() []string
+	LeafValues(leaf []byte) ([]string, error)
+}
+
+// exportRecord is a line of a JSONL export.
+type exportRecord struct {
+	Index    uint64 `json:"index"`
+	Leaf     []byte `json:"leaf"`
+	LeafHash string `json:"leaf_hash"`
+	Text     string `json:"text"`
+}
+
+// leafExporter writes exported leaves in one format.
+type leafExporter interface {
+	write(l *model.Leaf) error
+	close() error
+}
+
+// newLeafExporter returns an exporter of the client's leaves in format. The
+// jsonl and csv formats are written to the file out, or to stdout if out is
+// "-", and the files format writes a file for each leaf in the directory
+// out.
+func newLeafExporter(client logClient, format, out string) (leafExporter, error) {
+	if format == exportFiles {
+		if err := os.MkdirAll(out, 0o755); err != nil {
+			return nil, err
+		}
+		return &filesExporter{dir: out}, nil
+	}
+	var fields structuredLogClient
+	switch format {
+	case exportJSONL:
+	case exportCSV:
+		var ok bool
+		if fields, ok = client.(structuredLogClient); !ok {
+			return nil, fmt.Errorf("leaves of %s logs have no fields to export as CSV", client.GetLogType())
+		}
+	default:
+		return nil, fmt.Errorf("unknown export format %q, want one of %s, %s or %s", format, exportJSONL, exportCSV, exportFiles)
+	}
+	w := io.WriteCloser(nopWriteCloser{os.Stdout})
+	if out != "-" {
+		f, err := os.Create(out)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	if format == exportJSONL {
+		return &jsonlExporter{w: w, enc: json.NewEncoder(w), client: client}, nil
+	}
+	e := &csvExporter{w: w, csv: csv.NewWriter(w), client: fields}
+	if err := e.csv.Write(append([]string{"index", "leaf_hash"}, fields.LeafFields()...)); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+type jsonlExporter struct {
+	w      io.WriteCloser
+	enc    *json.Encoder
+	client logClient
+}
+
+func (e *jsonlExporter) write(l *model.Leaf) error {
+	return e.enc.Encode(exportRecord{
+		Index:    l.Index,
+		Leaf:     l.Contents,
+		LeafHash: hex.EncodeToString(l.LeafHash),
+		Text:     e.client.FormatLeaf(l.Contents),
+	})
+}
+
+func (e *jsonlExporter) close() error { return e.w.Close() }
+
+type csvExporter struct {
+	w      io.WriteCloser
+	csv    *csv.Writer
+	client structuredLogClient
+}
+
+func (e *csvExporter) write(l *model.Leaf) error {
+	values, err := e.client.LeafValues(l.Contents)
+	if err != nil {
+		return fmt.Errorf("leaf %d: %w", l.Index, err)
+	}
+	return e.csv.Write(append([]string{strconv.FormatUint(l.Index, 10), hex.EncodeToString(l.LeafHash)}, values...))
+}
+
+func (e *csvExporter) close() error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		_ = e.w.Close()
+		return err
+	}
+	return e.w.Close()
+}
+
+// filesExporter writes each leaf, as is, to a file named by its index.
+type filesExporter struct {
+	dir string
+}
+
+func (e *filesExporter) write(l *model.Leaf) error {
+	return os.WriteFile(filepath.Join(e.dir, strconv.FormatUint(l.Index, 10)), l.Contents, 0o644)
+}
+
+func (e *filesExporter) close() error { return nil }
+
+// exportLeaves exports the leaves from start up to, but not including, end,
+// proving each against the checkpoint. Leaves are fetched with GetLeaf, so
+// that consecutive leaves are read from the same bundle, and the export
+// fails at the first leaf that can't be verified. progress, if not nil, is
+// called after each leaf with the number exported so far.
+func exportLeaves(ctx context.Context, client logClient, cp *model.Checkpoint, start, end uint64, format, out string, progress func(uint64)) (uint64, error) {
+	if cp == nil || cp.Checkpoint == nil {
+		return 0, fmt.Errorf("no checkpoint loaded")
+	}
+	if start >= end || end > cp.Size {
+		return 0, fmt.Errorf("invalid range [%d, %d) for checkpoint size %d", start, end, cp.Size)
+	}
+	e, err := newLeafExporter(client, format, out)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for i := start; i < end; i++ {
+		if err := ctx.Err(); err != nil {
+			_ = e.close()
+			return n, err
+		}
+		l, err := client.GetLeaf(cp, i)
+		if err == nil && !l.Verified {
+			err = errors.New("not verified")
+		}
+		if err == nil {
+			err = e.write(l)
+		}
+		if err != nil {
+			_ = e.close()
+			return n, fmt.Errorf("leaf %d: %w", i, err)
+		}
+		n++
+		if progress != nil {
+			progress(n)
+		}
+	}
+	return n, e.close()
+}
+
+// parseExportRange parses the START and END arguments of an export.
+func parseExportRange(start, end string) (uint64, uint64, error) {
+	s, err := strconv.ParseUint(start, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start %q", start)
+	}
+	e, err := strconv.ParseUint(end, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid end %q", end)
+	}
+	return s, e, nil
+}
+
+// parseExportInput parses the TUI's export input, "START END FORMAT OUT".
+func parseExportInput(input string) (start, end uint64, format, out string, err error) {
+	f := strings.Fields(input)
+	if len(f) != 4 {
+		return 0, 0, "", "", fmt.Errorf("want START END FORMAT OUT, got %q", input)
+	}
+	start, end, err = parseExportRange(f[0], f[1])
+	return start, end, f[2], f[3], err
+}
+
+// runExport runs the export command, which exports leaves of the log chosen
+// with --origin, or else the custom log or first built-in log.
+func runExport(args []string) {
+	if len(args) != 3 {
+		klog.Exitf("Usage: woodpecker [flags] export [--export_format=jsonl|csv|files] START END OUT")
+	}
+	client := commandClient()
+	start, end, err := parseExportRange(args[0], args[1])
+	if err != nil {
+		klog.Exitf("%v", err)
+	}
+	cp, err := client.GetCheckpoint()
+	if err != nil {
+		klog.Exitf("Failed to fetch checkpoint of %q: %v", client.GetOrigin(), err)
+	}
+	n, err := exportLeaves(context.Background(), client, cp, start, end, *exportFormat, args[2], func(n uint64) {
+		if n%100 == 0 || n == end-start {
+			fmt.Fprintf(os.Stderr, "\rExported %d/%d leaves", n, end-start)
+		}
+	})
+	if err != nil {
+		klog.Exitf("Failed to export leaves of %q after %d leaves: %v", client.GetOrigin(), n, err)
+	}
+	fmt.Fprintf(os.Stderr, "\nExported %d leaves of %q, verified in tree size %d, as %s to %s\n", n, client.GetOrigin(), cp.Size, *exportFormat, args[2])
+}
+
+// exportDefaults returns the text to start the TUI's export input with: the
+// next 100 leaves from index, as JSONL.
+func exportDefaults(origin string, index, size uint64) string {
+	end := min(index+100, size)
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(origin)
+	return fmt.Sprintf("%d %d %s %s-%d-%d.%s", index, end, exportJSONL, name, index, end, exportJSONL)
+}
+
+// exportDoneMsg reports the result of an export started from the TUI.
+type exportDoneMsg struct {
+	n      uint64
+	format string
+	out    string
+	size   uint64
+	err    error
+}
+
+// exportCmd exports the leaves described by the export input, proving them
+// against the checkpoint that leaves are being proven against.
+func (m *Model) exportCmd(input string) tea.Cmd {
+	client := m.currentClient
+	cp := m.checkpoint
+	witnessed := m.proveWitnessed
+	if witnessed {
+		cp = m.witnessed
+	}
+	return func() tea.Msg {
+		start, end, format, out, err := parseExportInput(input)
+		if err != nil {
+			return exportDoneMsg{err: err}
+		}
+		if out == "-" {
+			// Writing to stdout would draw over the TUI.
+			return exportDoneMsg{err: errors.New("can't export to stdout from the TUI, use the export command")}
+		}
+		if witnessed && (cp == nil || end > cp.Size) {
+			return exportDoneMsg{err: fmt.Errorf("leaves up to %d are not yet witnessed", end)}
+		}
+		n, err := exportLeaves(context.Background(), client, cp, start, end, format, out, nil)
+		return exportDoneMsg{n: n, format: format, out: out, size: cp.Size, err: err}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/transparency-dev/merkle/rfc6962"
+)
+
+func TestExportLeaves(t *testing.T) {
+	const origin = "example.com/exported"
+	entries := testEntries(300, "")
+	files, vkey := newTestLog(t, "tiles", origin, entries)
+	srv := newTestLogServer(t, files)
+	client, err := newTLogTilesLogClient(srv.URL, origin, vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, err := client.GetCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("jsonl", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "leaves.jsonl")
+		n, err := exportLeaves(t.Context(), client, cp, 250, 300, exportJSONL, out, nil)
+		if err != nil || n != 50 {
+			t.Fatalf("exportLeaves = %d, %v; want 50 leaves", n, err)
+		}
+		f, err := os.Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var got []exportRecord
+		for s := bufio.NewScanner(f); s.Scan(); {
+			var r exportRecord
+			if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+				t.Fatalf("line %d: %v", len(got), err)
+			}
+			got = append(got, r)
+		}
+		if len(got) != 50 {
+			t.Fatalf("got %d records, want 50", len(got))
+		}
+		for i, r := range got {
+			idx := uint64(250 + i)
+			want := entries[idx]
+			if r.Index != idx || string(r.Leaf) != want || r.Text != want || r.LeafHash != hex.EncodeToString(rfc6962.DefaultHasher.HashLeaf([]byte(want))) {
+				t.Errorf("record %d = %+v, want leaf %d %q", i, r, idx, want)
+			}
+		}
+	})
+
+	t.Run("files", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "leaves")
+		if _, err := exportLeaves(t.Context(), client, cp, 0, 3, exportFiles, dir, nil); err != nil {
+			t.Fatalf("exportLeaves: %v", err)
+		}
+		for i := range 3 {
+			got, err := os.ReadFile(filepath.Join(dir, fmt.Sprint(i)))
+			if err != nil || string(got) != entries[i] {
+				t.Errorf("leaf file %d = %q, %v; want %q", i, got, err, entries[i])
+			}
+		}
+	})
+
+	t.Run("bundles fetched once", func(t *testing.T) {
+		srv.requests = make(map[string]int)
+		if _, err := exportLeaves(t.Context(), client, cp, 0, 256, exportFiles, t.TempDir(), nil); err != nil {
+			t.Fatalf("exportLeaves: %v", err)
+		}
+		layout, _ := layoutFor("tiles")
+		if got := srv.requests[layout.leafPaths(0, 256)[0]]; got > 1 {
+			t.Errorf("entry bundle fetched %d times, want once", got)
+		}
+	})
+
+	t.Run("csv needs fields", func(t *testing.T) {
+		if _, err := exportLeaves(t.Context(), client, cp, 0, 1, exportCSV, filepath.Join(t.TempDir(), "x.csv"), nil); err == nil {
+			t.Error("CSV export of a log without leaf fields succeeded, want error")
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		tampered, vkey := newTestLog(t, "tiles", origin, entries)
+		layout, _ := layoutFor("tiles")
+		p := layout.leafPaths(1, 44)[0]
+		tampered[p] = []byte(strings.Replace(string(tampered[p]), "entry 260", "entry X60", 1))
+		client, err := newTLogTilesLogClient(newTestLogServer(t, tampered).URL, origin, vkey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp, err := client.GetCheckpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := exportLeaves(t.Context(), client, cp, 250, 300, exportJSONL, filepath.Join(t.TempDir(), "x.jsonl"), nil)
+		if err == nil || n != 10 {
+			t.Errorf("exportLeaves of a tampered log = %d, %v; want a failure after 10 leaves", n, err)
+		}
+	})
+}
+
+func TestExportCSV(t *testing.T) {
+	const origin = "example.com/sumdb"
+	var entries []string
+	for i := range 3 {
+		entries = append(entries, fmt.Sprintf("example.com/mod v1.0.%d h1:hash%d=\nexample.com/mod v1.0.%d/go.mod h1:gomod%d=\n", i, i, i, i))
+	}
+	files, vkey := newTestLog(t, "sumdb", origin, entries)
+	client, err := newSumDBLogClient(newTestLogServer(t, files).URL, origin, vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, err := client.GetCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "modules.csv")
+	if _, err := exportLeaves(t.Context(), client, cp, 1, 3, exportCSV, out, nil); err != nil {
+		t.Fatalf("exportLeaves: %v", err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"index", "leaf_hash", "module", "version", "hash", "go_mod_hash"},
+		{"1", hex.EncodeToString(rfc6962.DefaultHasher.HashLeaf([]byte(entries[1]))), "example.com/mod", "v1.0.1", "h1:hash1=", "h1:gomod1="},
+		{"2", hex.EncodeToString(rfc6962.DefaultHasher.HashLeaf([]byte(entries[2]))), "example.com/mod", "v1.0.2", "h1:hash2=", "h1:gomod2="},
+	}
+	if fmt.Sprint(rows) != fmt.Sprint(want) {
+		t.Errorf("CSV rows = %q, want %q", rows, want)
+	}
+}
+
+func TestParseExportInput(t *testing.T) {
+	start, end, format, out, err := parseExportInput(" 10 20  csv /tmp/out.csv ")
+	if err != nil || start != 10 || end != 20 || format != "csv" || out != "/tmp/out.csv" {
+		t.Errorf("parseExportInput = %d, %d, %q, %q, %v", start, end, format, out, err)
+	}
+	for _, input := range []string{"10 20 csv", "x 20 csv out", "10 -1 csv out"} {
+		if _, _, _, _, err := parseExportInput(input); err == nil {
+			t.Errorf("parseExportInput(%q) succeeded, want error", input)
+		}
+	}
+	if got, want := exportDefaults("example.com/log", 250, 300), "250 300 jsonl example.com_log-250-300.jsonl"; got != want {
+		t.Errorf("exportDefaults = %q, want %q", got, want)
+	}
+}
+
+func TestExportView(t *testing.T) {
+	const origin = "example.com/tui-export"
+	entries := testEntries(20, "")
+	files, vkey := newTestLog(t, "tiles", origin, entries)
+	client, err := newTLogTilesLogClient(newTestLogServer(t, files).URL, origin, vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel([]string{origin}, map[string]logClient{origin: client}, nil, nil, origin, modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	processCmds(t, m, m.fetchCheckpointCmd())
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	if m.activeView != "export" {
+		t.Fatalf("activeView = %q, want export", m.activeView)
+	}
+	if got, want := m.exportInput.Value(), exportDefaults(origin, 19, 20); got != want {
+		t.Errorf("export input = %q, want %q", got, want)
+	}
+	if !strings.Contains(m.View(), "Export Verified Leaves") {
+		t.Error("export view not rendered")
+	}
+
+	dir := filepath.Join(t.TempDir(), "leaves")
+	m.exportInput.SetValue(fmt.Sprintf("5 8 files %s", dir))
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.activeView != "leaf" || m.exporting {
+		t.Errorf("after export activeView = %q, exporting %v; want leaf and done", m.activeView, m.exporting)
+	}
+	if want := fmt.Sprintf("Exported 3 leaves, verified in tree size 20, as files to %s", dir); m.statusMsg != want {
+		t.Errorf("statusMsg = %q, want %q", m.statusMsg, want)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "7")); err != nil || string(got) != entries[7] {
+		t.Errorf("exported leaf 7 = %q, %v", got, err)
+	}
+
+	// Exporting to stdout would corrupt the screen.
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	m.exportInput.SetValue("5 8 jsonl -")
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.statusMsg, "can't export to stdout") {
+		t.Errorf("statusMsg = %q, want a stdout error", m.statusMsg)
+	}
+
+	// Escape cancels without exporting.
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.activeView != "leaf" {
+		t.Errorf("activeView = %q, want leaf", m.activeView)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultHTTPRetries is how many times a request is retried after a
+	// 429 or 5xx response, unless set with --http_retries.
+	defaultHTTPRetries = 3
+	// retryBaseDelay is the delay before the first retry when the server
+	// doesn't say how long to wait. It doubles for each further retry.
+	retryBaseDelay = 500 * time.Millisecond
+	// maxRetryDelay is the longest a request is delayed before retrying. If
+	// the server asks for a longer wait, its response is returned instead.
+	maxRetryDelay = 30 * time.Second
+)
+
+// httpStats collects statistics for every request made with httpClient.
+var httpStats = newFetchStats()
+
+// fetchTransport is an http.RoundTripper which retries GET requests that
+// fail with 429 or 5xx, limits the rate of requests to each host, asks for
+// gzip-compressed responses, and records statistics about each attempt.
+type fetchTransport struct {
+	next    http.RoundTripper
+	retries int
+	// rate is the most requests per second made to each host, or 0 for no
+	// limit.
+	rate  float64
+	stats *fetchStats
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu       sync.Mutex
+	nextSlot map[string]time.Time
+}
+
+func newFetchTransport(next http.RoundTripper, retries int, rate float64, stats *fetchStats) *fetchTransport {
+	return &fetchTransport{
+		next:     next,
+		retries:  retries,
+		rate:     rate,
+		stats:    stats,
+		now:      time.Now,
+		sleep:    sleepContext,
+		nextSlot: make(map[string]time.Time),
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *fetchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	retryable := req.Method == http.MethodGet || req.Method == http.MethodHead
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(req.Context(), host); err != nil {
+			return nil, err
+		}
+		r := req.Clone(req.Context())
+		acceptGzip := r.Header.Get("Accept-Encoding") == "" && r.Header.Get("Range") == ""
+		if acceptGzip {
+			r.Header.Set("Accept-Encoding", "gzip")
+		}
+		start := t.now()
+		resp, err := t.next.RoundTrip(r)
+		latency := t.now().Sub(start)
+		if err != nil {
+			t.stats.record(host, latency, true)
+			return nil, err
+		}
+		failed := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		t.stats.record(host, latency, failed || (resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound))
+		if failed && retryable && attempt < t.retries {
+			delay, ok := retryDelay(resp.Header, attempt, t.now())
+			if ok {
+				// Drain the body so that the connection can be reused.
+				_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+				_ = resp.Body.Close()
+				t.stats.retried(host)
+				if err := t.sleep(req.Context(), delay); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		resp.Body = &countingReader{r: resp.Body, n: &t.stats.host(host).bytes}
+		if acceptGzip && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			zr, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				_ = resp.Body.Close()
+				return nil, fmt.Errorf("invalid gzip response from %s: %w", host, err)
+			}
+			resp.Body = &gzipBody{Reader: zr, body: resp.Body}
+			resp.Header.Del("Content-Encoding")
+			resp.Header.Del("Content-Length")
+			resp.ContentLength = -1
+			resp.Uncompressed = true
+		}
+		return resp, nil
+	}
+}
+
+// wait blocks until the rate limit allows another request to host.
+func (t *fetchTransport) wait(ctx context.Context, host string) error {
+	if t.rate <= 0 {
+		return nil
+	}
+	interval := time.Duration(float64(time.Second) / t.rate)
+	t.mu.Lock()
+	now := t.now()
+	slot := t.nextSlot[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	t.nextSlot[host] = slot.Add(interval)
+	t.mu.Unlock()
+	if d := slot.Sub(now); d > 0 {
+		return t.sleep(ctx, d)
+	}
+	return nil
+}
+
+// retryDelay returns how long to wait before retry number attempt+1. This is
+// the server's Retry-After if it gave one, or else an exponential backoff
+// with jitter. It returns false if the server asked to wait too long.
+func retryDelay(h http.Header, attempt int, now time.Time) (time.Duration, bool) {
+	if ra := h.Get("Retry-After"); ra != "" {
+		var d time.Duration
+		if s, err := strconv.Atoi(ra); err == nil {
+			d = time.Duration(s) * time.Second
+		} else if at, err := http.ParseTime(ra); err == nil {
+			d = at.Sub(now)
+		}
+		if d > maxRetryDelay {
+			return 0, false
+		}
+		return max(d, 0), true
+	}
+	d := min(retryBaseDelay<<attempt, maxRetryDelay)
+	// Pick a delay between d/2 and d so that clients don't retry in step.
+	return d/2 + rand.N(d/2+1), true
+}
+
+type countingReader struct {
+	r io.ReadCloser
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func (c *countingReader) Close() error { return c.r.Close() }
+
+// gzipBody decompresses a response body, closing the underlying body when
+// it is closed.
+type gzipBody struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (g *gzipBody) Close() error {
+	_ = g.Reader.Close()
+	return g.body.Close()
+}
+
+// fetchStats counts the HTTP requests made to each host.
+type fetchStats struct {
+	mu    sync.Mutex
+	hosts map[string]*hostStats
+}
+
+type hostStats struct {
+	requests   int
+	retries    int
+	errors     int
+	latency    time.Duration
+	maxLatency time.Duration
+	// bytes is updated as response bodies are read.
+	bytes atomic.Int64
+}
+
+func newFetchStats() *fetchStats {
+	return &fetchStats{hosts: make(map[string]*hostStats)}
+}
+
+func (s *fetchStats) host(host string) *hostStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.hosts[host]
+	if !ok {
+		h = &hostStats{}
+		s.hosts[host] = h
+	}
+	return h
+}
+
+func (s *fetchStats) record(host string, latency time.Duration, failed bool) {
+	h := s.host(host)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h.requests++
+	h.latency += latency
+	h.maxLatency = max(h.maxLatency, latency)
+	if failed {
+		h.errors++
+	}
+}
+
+func (s *fetchStats) retried(host string) {
+	h := s.host(host)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h.retries++
+}
+
+// render draws a table of the statistics for each host, busiest first.
+func (s *fetchStats) render() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hosts := make([]string, 0, len(s.hosts))
+	for h := range s.hosts {
+		hosts = append(hosts, h)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		a, b := s.hosts[hosts[i]], s.hosts[hosts[j]]
+		if a.requests != b.requests {
+			return a.requests > b.requests
+		}
+		return hosts[i] < hosts[j]
+	})
+	var sb strings.Builder
+	fmt.Fprintf(&sb, " %-40s %9s %8s %7s %10s %9s %9s\n", "Host", "Requests", "Retries", "Errors", "Bytes", "Avg", "Max")
+	if len(hosts) == 0 {
+		sb.WriteString(" No requests made yet.\n")
+	}
+	for _, name := range hosts {
+		h := s.hosts[name]
+		var avg time.Duration
+		if h.requests > 0 {
+			avg = h.latency / time.Duration(h.requests)
+		}
+		fmt.Fprintf(&sb, " %-40s %9d %8d %7d %10s %9s %9s\n", limitText(name, 40, 1), h.requests, h.retries, h.errors,
+			formatBytes(h.bytes.Load()), avg.Round(time.Millisecond), h.maxLatency.Round(time.Millisecond))
+	}
+	return sb.String()
+}
+
+// formatBytes returns n in B, KiB, MiB or GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 2; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMG"[exp])
+}
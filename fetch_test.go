@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestFetchTransport returns a fetchTransport which records its sleeps
+// instead of sleeping.
+func newTestFetchTransport(retries int, rate float64) (*fetchTransport, *[]time.Duration) {
+	var slept []time.Duration
+	t := newFetchTransport(http.DefaultTransport, retries, rate, newFetchStats())
+	t.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return t, &slept
+}
+
+func TestFetchTransportRetries(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		method      string
+		failures    int
+		retryAfter  string
+		wantStatus  int
+		wantAttempt int
+		wantSleep   time.Duration // 0 to not check
+	}{
+		{name: "success after retries", method: http.MethodGet, failures: 2, wantStatus: 200, wantAttempt: 3},
+		{name: "retries exhausted", method: http.MethodGet, failures: 10, wantStatus: 503, wantAttempt: 4},
+		{name: "retry after", method: http.MethodGet, failures: 1, retryAfter: "7", wantStatus: 200, wantAttempt: 2, wantSleep: 7 * time.Second},
+		{name: "retry after too long", method: http.MethodGet, failures: 1, retryAfter: "3600", wantStatus: 503, wantAttempt: 1},
+		{name: "post not retried", method: http.MethodPost, failures: 1, wantStatus: 503, wantAttempt: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tc.failures {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = io.WriteString(w, "ok")
+			}))
+			defer srv.Close()
+
+			transport, slept := newTestFetchTransport(3, 0)
+			req, err := http.NewRequest(tc.method, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := (&http.Client{Transport: transport}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tc.wantStatus || attempts != tc.wantAttempt {
+				t.Errorf("got status %d after %d attempts, want %d after %d", resp.StatusCode, attempts, tc.wantStatus, tc.wantAttempt)
+			}
+			if tc.wantSleep != 0 && (len(*slept) != 1 || (*slept)[0] != tc.wantSleep) {
+				t.Errorf("slept %v, want [%v]", *slept, tc.wantSleep)
+			}
+			h := transport.stats.host(req.URL.Host)
+			if h.requests != tc.wantAttempt || h.retries != tc.wantAttempt-1 {
+				t.Errorf("stats recorded %d requests, %d retries; want %d, %d", h.requests, h.retries, tc.wantAttempt, tc.wantAttempt-1)
+			}
+		})
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		d, ok := retryDelay(http.Header{}, attempt, time.Now())
+		want := min(retryBaseDelay<<attempt, maxRetryDelay)
+		if !ok || d < want/2 || d > want {
+			t.Errorf("retryDelay(attempt %d) = %v, %v; want between %v and %v", attempt, d, ok, want/2, want)
+		}
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := http.Header{"Retry-After": {now.Add(5 * time.Second).Format(http.TimeFormat)}}
+	if d, ok := retryDelay(h, 0, now); !ok || d != 5*time.Second {
+		t.Errorf("retryDelay with an HTTP date = %v, %v; want 5s", d, ok)
+	}
+}
+
+func TestFetchTransportGzip(t *testing.T) {
+	body := strings.Repeat("compressible ", 1000)
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = io.WriteString(zw, body)
+	_ = zw.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			_, _ = io.WriteString(w, body)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(compressed.Bytes())
+	}))
+	defer srv.Close()
+
+	transport, _ := newTestFetchTransport(0, 0)
+	resp, err := (&http.Client{Transport: transport}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body {
+		t.Errorf("got %d bytes, want the %d decompressed bytes", len(got), len(body))
+	}
+	u, _ := url.Parse(srv.URL)
+	if got, want := transport.stats.host(u.Host).bytes.Load(), int64(compressed.Len()); got != want {
+		t.Errorf("recorded %d bytes, want the %d compressed bytes", got, want)
+	}
+}
+
+func TestFetchTransportRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	transport, slept := newTestFetchTransport(0, 4)
+	transport.now = func() time.Time { return now }
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+	want := []time.Duration{250 * time.Millisecond, 500 * time.Millisecond}
+	if len(*slept) != len(want) || (*slept)[0] != want[0] || (*slept)[1] != want[1] {
+		t.Errorf("slept %v, want %v", *slept, want)
+	}
+}
+
+func TestReadHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, "bucket is private\n")
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL + "/checkpoint")
+	_, err := readHTTP(context.Background(), httpClient, u)
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "bucket is private") {
+		t.Errorf("readHTTP() = %v, want an error with the status and body", err)
+	}
+}
+
+func TestFetchStatsOverlay(t *testing.T) {
+	m := NewModel([]string{"a"}, map[string]logClient{"a": &mockLogClient{}}, nil, nil, "a", modelOptions{})
+	m.fetchStats = newFetchStats()
+	m.fetchStats.record("example.com", 120*time.Millisecond, false)
+	m.fetchStats.record("example.com", 80*time.Millisecond, true)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+	if m.activeView != "stats" {
+		t.Fatalf("activeView = %q, want stats", m.activeView)
+	}
+	view := m.View()
+	for _, want := range []string{"HTTP Statistics", "example.com", "100ms", "120ms"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("overlay does not contain %q", want)
+		}
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.activeView != "leaf" {
+		t.Errorf("activeView = %q, want leaf", m.activeView)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mhutchinson/woodpecker/model"
+)
+
+const (
+	// maxFollowBatch is the most leaves fetched for a single new checkpoint
+	// in follow mode. If more leaves were added, only the newest are fetched.
+	maxFollowBatch = 50
+	// maxFollowLeaves is the number of leaves kept in the follow stream.
+	maxFollowLeaves = 500
+)
+
+// followLeaf is a newly integrated leaf shown in follow mode. If skipped is
+// non-zero, it instead marks that many leaves that weren't fetched.
+type followLeaf struct {
+	index   uint64
+	summary string
+	err     error
+	skipped uint64
+}
+
+// followLeavesMsg carries the leaves added by a new checkpoint. skipped is
+// the number of older new leaves that weren't fetched.
+type followLeavesMsg struct {
+	origin  string
+	leaves  []followLeaf
+	skipped uint64
+}
+
+// fetchFollowLeaves fetches the leaves in [from, cp.Size), proving each
+// against cp. At most maxFollowBatch of the newest leaves are fetched.
+func fetchFollowLeaves(client logClient, cp *model.Checkpoint, from uint64) followLeavesMsg {
+	msg := followLeavesMsg{origin: client.GetOrigin()}
+	if cp.Size-from > maxFollowBatch {
+		msg.skipped = cp.Size - from - maxFollowBatch
+		from = cp.Size - maxFollowBatch
+	}
+	for i := from; i < cp.Size; i++ {
+		l := followLeaf{index: i}
+		leaf, err := client.GetLeaf(cp, i)
+		l.err = err
+		if leaf != nil {
+			l.summary = summarizeLeaf(client.FormatLeaf(leaf.Contents))
+		}
+		msg.leaves = append(msg.leaves, l)
+	}
+	return msg
+}
+
+// summarizeLeaf returns the first non-blank line of a formatted leaf.
+func summarizeLeaf(formatted string) string {
+	for _, line := range strings.Split(formatted, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// followRate returns the number of leaves per second added to the log since
+// follow mode was turned on.
+func followRate(startSize, size uint64, start, now time.Time) float64 {
+	elapsed := now.Sub(start).Seconds()
+	if elapsed <= 0 || size < startSize {
+		return 0
+	}
+	return float64(size-startSize) / elapsed
+}
+
+// renderFollow draws the follow stream with the newest leaf at the bottom,
+// showing no more than height lines.
+func renderFollow(leaves []followLeaf, width, height int) string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+	bad := lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171"))
+	if len(leaves) == 0 {
+		return dim.Render("Waiting for new leaves...")
+	}
+	if len(leaves) > height {
+		leaves = leaves[len(leaves)-height:]
+	}
+	lines := make([]string, 0, len(leaves))
+	for _, l := range leaves {
+		if l.skipped > 0 {
+			lines = append(lines, dim.Render(fmt.Sprintf("… %d leaves not shown", l.skipped)))
+			continue
+		}
+		prefix := fmt.Sprintf("#%-10d ", l.index)
+		if l.err != nil && l.summary == "" {
+			lines = append(lines, prefix+bad.Render(limitText(l.err.Error(), width-len(prefix), 1)))
+			continue
+		}
+		line := prefix + limitText(l.summary, width-len(prefix), 1)
+		if l.err != nil {
+			line = bad.Render(prefix+"✗ ") + limitText(l.summary, width-len(prefix)-4, 1)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
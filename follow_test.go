@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFollowMode(t *testing.T) {
+	client := &dashboardLogClient{origin: "a", size: 10}
+	m := NewModel([]string{"a"}, map[string]logClient{"a": client}, nil, nil, "a", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	processCmds(t, m, m.fetchCheckpointCmd())
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	if m.activeView != "follow" {
+		t.Fatalf("activeView = %q, want follow", m.activeView)
+	}
+
+	client.size = 13
+	processCmds(t, m, m.fetchCheckpointCmd())
+	if got, want := len(m.followLeaves), 3; got != want {
+		t.Fatalf("got %d leaves streamed, want %d", got, want)
+	}
+	for i, l := range m.followLeaves {
+		if want := uint64(10 + i); l.index != want || l.summary != "leaf" {
+			t.Errorf("leaf %d = #%d %q, want #%d %q", i, l.index, l.summary, want, "leaf")
+		}
+	}
+
+	// Only the newest leaves of a large batch are fetched.
+	client.size = 100
+	processCmds(t, m, m.fetchCheckpointCmd())
+	if got, want := len(m.followLeaves), 3+1+maxFollowBatch; got != want {
+		t.Fatalf("got %d entries, want %d", got, want)
+	}
+	if got, want := m.followLeaves[3].skipped, uint64(100-13-maxFollowBatch); got != want {
+		t.Errorf("got %d leaves skipped, want %d", got, want)
+	}
+
+	// Turning follow mode off shows the newest leaf streamed.
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	if m.activeView != "leaf" {
+		t.Errorf("activeView = %q, want leaf", m.activeView)
+	}
+	if got, want := m.leaf.Index, uint64(99); got != want {
+		t.Errorf("leaf index = %d, want %d", got, want)
+	}
+}
+
+func TestFollowRate(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got, want := followRate(100, 160, start, start.Add(30*time.Second)), 2.0; got != want {
+		t.Errorf("followRate = %v, want %v", got, want)
+	}
+	if got := followRate(100, 160, start, start); got != 0 {
+		t.Errorf("followRate with no time elapsed = %v, want 0", got)
+	}
+}
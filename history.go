@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxHistory is the number of checkpoints remembered for each log. Older
+// checkpoints are forgotten first.
+const maxHistory = 1000
+
+// checkpointObservation is a checkpoint of a log as first observed.
+type checkpointObservation struct {
+	size     uint64
+	hash     []byte
+	observed time.Time
+	// witnesses is the largest number of verified cosignatures seen on the
+	// checkpoint.
+	witnesses int
+}
+
+// checkpointHistory is the checkpoints observed for a log, oldest first.
+type checkpointHistory struct {
+	entries []checkpointObservation
+}
+
+// observe records the log's checkpoint, unless it is the same as the last
+// one observed.
+func (h *checkpointHistory) observe(size uint64, hash []byte, at time.Time) {
+	if n := len(h.entries); n > 0 && h.entries[n-1].size == size && bytes.Equal(h.entries[n-1].hash, hash) {
+		return
+	}
+	h.entries = append(h.entries, checkpointObservation{size: size, hash: hash, observed: at})
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+}
+
+// witnessed records that the checkpoint with the given size and hash has n
+// verified cosignatures. This is ignored if the checkpoint wasn't observed.
+func (h *checkpointHistory) witnessed(size uint64, hash []byte, n int) {
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		e := &h.entries[i]
+		if e.size == size && bytes.Equal(e.hash, hash) {
+			e.witnesses = max(e.witnesses, n)
+			return
+		}
+	}
+}
+
+// firstNewLeaf returns the index of the first leaf added between the
+// checkpoint before entry i and entry i itself. It returns false if there
+// is no earlier checkpoint or the log didn't grow.
+func (h *checkpointHistory) firstNewLeaf(i int) (uint64, bool) {
+	if i <= 0 || i >= len(h.entries) {
+		return 0, false
+	}
+	prev, e := h.entries[i-1], h.entries[i]
+	if e.size <= prev.size {
+		return 0, false
+	}
+	return prev.size, true
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline plots the sizes of the last width checkpoints, scaled between
+// the smallest and largest of them.
+func (h *checkpointHistory) sparkline(width int) string {
+	entries := h.entries
+	if len(entries) > width {
+		entries = entries[len(entries)-width:]
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	lo, hi := entries[0].size, entries[0].size
+	for _, e := range entries {
+		lo, hi = min(lo, e.size), max(hi, e.size)
+	}
+	var sb strings.Builder
+	for _, e := range entries {
+		level := 0
+		if hi > lo {
+			level = int((e.size - lo) * uint64(len(sparkBlocks)-1) / (hi - lo))
+		}
+		sb.WriteRune(sparkBlocks[level])
+	}
+	return sb.String()
+}
+
+// renderHistory draws the checkpoints observed, newest first, highlighting
+// the row under the cursor. The cursor counts rows from the newest.
+func renderHistory(h *checkpointHistory, cursor int, now time.Time) string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+	var sb strings.Builder
+	sb.WriteString(dim.Render(fmt.Sprintf(" %-20s %-8s %-12s %-10s %-18s %s", "Observed", "Age", "Size", "Added", "Root hash", "Witnesses")))
+	if h == nil || len(h.entries) == 0 {
+		sb.WriteString("\n No checkpoints observed yet.")
+		return sb.String()
+	}
+	for row := 0; row < len(h.entries); row++ {
+		i := len(h.entries) - 1 - row
+		e := h.entries[i]
+		added := "-"
+		if from, ok := h.firstNewLeaf(i); ok {
+			added = fmt.Sprintf("+%d", e.size-from)
+		}
+		witnesses := "-"
+		if e.witnesses > 0 {
+			witnesses = fmt.Sprintf("%d", e.witnesses)
+		}
+		line := fmt.Sprintf(" %-20s %-8s %-12d %-10s %-18s %s",
+			e.observed.UTC().Format(time.DateTime), formatAge(now.Sub(e.observed)), e.size, added, fmt.Sprintf("%.8x…", e.hash), witnesses)
+		if row == cursor {
+			line = jsonCursorStyle.Render(line)
+		}
+		sb.WriteString("\n" + line)
+	}
+	return sb.String()
+}
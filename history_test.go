@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCheckpointHistory(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	hash := func(b byte) []byte { return bytes.Repeat([]byte{b}, 32) }
+	h := &checkpointHistory{}
+	h.observe(10, hash(1), start)
+	h.observe(10, hash(1), start.Add(time.Minute))
+	h.observe(15, hash(2), start.Add(2*time.Minute))
+	h.observe(15, hash(3), start.Add(3*time.Minute))
+	h.observe(30, hash(4), start.Add(4*time.Minute))
+	if got, want := len(h.entries), 4; got != want {
+		t.Fatalf("got %d entries, want %d as repeated checkpoints are not recorded", got, want)
+	}
+	if !h.entries[0].observed.Equal(start) {
+		t.Errorf("first entry observed at %v, want %v", h.entries[0].observed, start)
+	}
+
+	h.witnessed(15, hash(2), 2)
+	h.witnessed(15, hash(2), 1)
+	h.witnessed(99, hash(9), 5)
+	if got := h.entries[1].witnesses; got != 2 {
+		t.Errorf("got %d witnesses, want the most seen, 2", got)
+	}
+
+	for _, tc := range []struct {
+		i      int
+		want   uint64
+		wantOK bool
+	}{
+		{i: 0},
+		{i: 1, want: 10, wantOK: true},
+		// Same size with a different root hash adds no leaves.
+		{i: 2},
+		{i: 3, want: 15, wantOK: true},
+	} {
+		got, ok := h.firstNewLeaf(tc.i)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("firstNewLeaf(%d) = %d, %v; want %d, %v", tc.i, got, ok, tc.want, tc.wantOK)
+		}
+	}
+
+	if got, want := h.sparkline(10), "▁▂▂█"; got != want {
+		t.Errorf("sparkline(10) = %q, want %q", got, want)
+	}
+	if got, want := h.sparkline(2), "▁█"; got != want {
+		t.Errorf("sparkline(2) = %q, want %q", got, want)
+	}
+}
+
+func TestHistoryJumpToFirstNewLeaf(t *testing.T) {
+	client := &dashboardLogClient{origin: "a", size: 10}
+	m := NewModel([]string{"a"}, map[string]logClient{"a": client}, nil, nil, "a", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	processCmds(t, m, m.fetchCheckpointCmd())
+	client.size = 25
+	processCmds(t, m, m.fetchCheckpointCmd())
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}})
+	if m.activeView != "history" {
+		t.Fatalf("activeView = %q, want history", m.activeView)
+	}
+	// The newest checkpoint is first, and added leaves 10 to 24.
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.activeView != "leaf" {
+		t.Errorf("activeView = %q, want leaf", m.activeView)
+	}
+	if got, want := m.leaf.Index, uint64(10); got != want {
+		t.Errorf("leaf index = %d, want %d", got, want)
+	}
+}
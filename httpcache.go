@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries is the number of responses kept by cachingTransport.
+// The oldest entries are evicted first.
+const maxCacheEntries = 256
+
+// cachingTransport is an http.RoundTripper which honours the ETag and
+// Cache-Control max-age headers of GET responses. Fresh responses are served
+// from memory, and stale ones are revalidated with If-None-Match.
+//
+// This lets the checkpoint be polled often without refetching it from logs
+// which say how long it stays valid.
+type cachingTransport struct {
+	next http.RoundTripper
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	order   []string
+}
+
+type cacheEntry struct {
+	etag    string
+	expires time.Time
+	header  http.Header
+	body    []byte
+}
+
+func newCachingTransport(next http.RoundTripper) *cachingTransport {
+	return &cachingTransport{
+		next:    next,
+		now:     time.Now,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.next.RoundTrip(req)
+	}
+	key := req.URL.String()
+	t.mu.Lock()
+	e := t.entries[key]
+	t.mu.Unlock()
+
+	if e != nil && t.now().Before(e.expires) {
+		return e.response(req), nil
+	}
+	if e != nil && e.etag != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", e.etag)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotModified && e != nil:
+		_ = resp.Body.Close()
+		if maxAge, ok := cacheMaxAge(resp.Header); ok {
+			t.mu.Lock()
+			e.expires = t.now().Add(maxAge)
+			t.mu.Unlock()
+		}
+		return e.response(req), nil
+	case resp.StatusCode != http.StatusOK:
+		return resp, nil
+	}
+
+	maxAge, cacheable := cacheMaxAge(resp.Header)
+	etag := resp.Header.Get("ETag")
+	if !cacheable || (maxAge <= 0 && etag == "") {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	t.store(key, &cacheEntry{
+		etag:    etag,
+		expires: t.now().Add(maxAge),
+		header:  resp.Header.Clone(),
+		body:    body,
+	})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (t *cachingTransport) store(key string, e *cacheEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.entries[key]; !ok {
+		t.order = append(t.order, key)
+	}
+	t.entries[key] = e
+	for len(t.order) > maxCacheEntries {
+		delete(t.entries, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+// response returns a 200 response for req with the cached body.
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// cacheMaxAge returns how long a response stays fresh according to its
+// Cache-Control and Age headers, and false if it must not be cached at all.
+func cacheMaxAge(h http.Header) (time.Duration, bool) {
+	var maxAge time.Duration
+	for _, d := range strings.Split(h.Get("Cache-Control"), ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch {
+		case d == "no-store":
+			return 0, false
+		case d == "no-cache":
+			return 0, true
+		case strings.HasPrefix(d, "max-age="):
+			if s, err := strconv.Atoi(strings.TrimPrefix(d, "max-age=")); err == nil && s > 0 {
+				maxAge = time.Duration(s) * time.Second
+			}
+		}
+	}
+	if age, err := strconv.Atoi(h.Get("Age")); err == nil && age > 0 {
+		maxAge -= time.Duration(age) * time.Second
+	}
+	return max(maxAge, 0), true
+}
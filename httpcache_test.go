@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCachingTransport(t *testing.T) {
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		case "/etag":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("ETag", `"v1"`)
+		}
+		_, _ = io.WriteString(w, "body of "+r.URL.Path)
+	}))
+	defer srv.Close()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	transport := newCachingTransport(http.DefaultTransport)
+	transport.now = func() time.Time { return now }
+	client := &http.Client{Transport: transport}
+	get := func(path string) {
+		t.Helper()
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(b) != "body of "+path {
+			t.Errorf("GET %s = %d %q", path, resp.StatusCode, b)
+		}
+	}
+
+	for _, tc := range []struct {
+		path            string
+		wantRequests    int
+		wantNotModified int
+	}{
+		// Served from the cache until max-age has passed.
+		{path: "/fresh", wantRequests: 1},
+		{path: "/etag", wantRequests: 2, wantNotModified: 1},
+		{path: "/nostore", wantRequests: 2},
+	} {
+		requests, notModified = 0, 0
+		get(tc.path)
+		get(tc.path)
+		if requests != tc.wantRequests || notModified != tc.wantNotModified {
+			t.Errorf("%s: got %d requests, %d not modified; want %d, %d", tc.path, requests, notModified, tc.wantRequests, tc.wantNotModified)
+		}
+	}
+
+	requests = 0
+	now = now.Add(2 * time.Minute)
+	get("/fresh")
+	if requests != 1 {
+		t.Errorf("got %d requests for an expired response, want 1", requests)
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// logHTTPConfigs holds the HTTP settings from --log_http_config, keyed by
+// origin. Settings for every log have the empty origin.
+var logHTTPConfigs map[string]*logHTTPConfig
+
+// httpTransport is the transport that every HTTP client is built on.
+var httpTransport = http.DefaultTransport.(*http.Transport).Clone()
+
+// logHTTPConfig is how to reach a log's HTTP endpoints, for logs behind an
+// authenticating proxy or with a private CA.
+type logHTTPConfig struct {
+	headers http.Header
+	// The bearer token is read from tokenFile or the environment variable
+	// tokenEnv, so that it isn't on the command line.
+	tokenFile string
+	tokenEnv  string
+	certFile  string
+	keyFile   string
+	rootCAs   []string
+	proxy     *url.URL
+}
+
+// loadHTTPConfig reads a file of per-log HTTP settings. See
+// parseHTTPConfig.
+func loadHTTPConfig(path string) (map[string]*logHTTPConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := parseHTTPConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cs, nil
+}
+
+// parseHTTPConfig parses per-log HTTP settings. Each line is one of:
+//
+//	log ORIGIN
+//	header NAME VALUE
+//	bearer-token-file PATH
+//	bearer-token-env VARIABLE
+//	client-cert CERT_FILE KEY_FILE
+//	root-ca PATH
+//	proxy URL
+//
+// Settings apply to the log named by the preceding log line, or to every
+// log if they come before any log line. A log's settings override those
+// for every log, except that headers and root CAs are added to them.
+func parseHTTPConfig(b []byte) (map[string]*logHTTPConfig, error) {
+	cs := map[string]*logHTTPConfig{"": {}}
+	c := cs[""]
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for lineNum := 1; sc.Scan(); lineNum++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		args := strings.Fields(rest)
+		var err error
+		switch keyword {
+		case "log":
+			if rest == "" {
+				err = fmt.Errorf("log: expected <origin>")
+			} else if _, ok := cs[rest]; ok {
+				err = fmt.Errorf("log %q configured more than once", rest)
+			} else {
+				c = &logHTTPConfig{}
+				cs[rest] = c
+			}
+		case "header":
+			name, value, _ := strings.Cut(rest, " ")
+			if name == "" || strings.TrimSpace(value) == "" {
+				err = fmt.Errorf("header: expected <name> <value>")
+			} else {
+				if c.headers == nil {
+					c.headers = http.Header{}
+				}
+				c.headers.Add(name, strings.TrimSpace(value))
+			}
+		case "bearer-token-file", "bearer-token-env", "root-ca", "proxy":
+			if len(args) != 1 {
+				err = fmt.Errorf("%s: expected one argument", keyword)
+				break
+			}
+			switch keyword {
+			case "bearer-token-file":
+				c.tokenFile = args[0]
+			case "bearer-token-env":
+				c.tokenEnv = args[0]
+			case "root-ca":
+				c.rootCAs = append(c.rootCAs, args[0])
+			case "proxy":
+				if c.proxy, err = url.Parse(args[0]); err == nil && c.proxy.Host == "" {
+					err = fmt.Errorf("proxy: invalid URL %q", c.proxy.Redacted())
+				}
+			}
+		case "client-cert":
+			if len(args) != 2 {
+				err = fmt.Errorf("client-cert: expected <cert file> <key file>")
+			} else {
+				c.certFile, c.keyFile = args[0], args[1]
+			}
+		default:
+			err = fmt.Errorf("unknown keyword %q", keyword)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// merge returns the settings of o applied on top of c.
+func (c *logHTTPConfig) merge(o *logHTTPConfig) *logHTTPConfig {
+	m := *c
+	m.headers = c.headers.Clone()
+	m.rootCAs = append([]string{}, c.rootCAs...)
+	if o == nil {
+		return &m
+	}
+	for name, vs := range o.headers {
+		if m.headers == nil {
+			m.headers = http.Header{}
+		}
+		m.headers[name] = vs
+	}
+	if o.tokenFile != "" || o.tokenEnv != "" {
+		m.tokenFile, m.tokenEnv = o.tokenFile, o.tokenEnv
+	}
+	if o.certFile != "" {
+		m.certFile, m.keyFile = o.certFile, o.keyFile
+	}
+	m.rootCAs = append(m.rootCAs, o.rootCAs...)
+	if o.proxy != nil {
+		m.proxy = o.proxy
+	}
+	return &m
+}
+
+// String describes the settings without revealing any secrets.
+func (c *logHTTPConfig) String() string {
+	var parts []string
+	names := make([]string, 0, len(c.headers))
+	for name := range c.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, "header "+name+"=[redacted]")
+	}
+	if c.tokenFile != "" {
+		parts = append(parts, "bearer token from "+c.tokenFile)
+	}
+	if c.tokenEnv != "" {
+		parts = append(parts, "bearer token from $"+c.tokenEnv)
+	}
+	if c.certFile != "" {
+		parts = append(parts, "client certificate "+c.certFile)
+	}
+	for _, ca := range c.rootCAs {
+		parts = append(parts, "root CA "+ca)
+	}
+	if c.proxy != nil {
+		parts = append(parts, "proxy "+c.proxy.Redacted())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// httpConfigFor returns the HTTP settings for the log, or nil if there are
+// none.
+func httpConfigFor(origin string) *logHTTPConfig {
+	if logHTTPConfigs == nil {
+		return nil
+	}
+	d, ok := logHTTPConfigs[""]
+	if !ok {
+		d = &logHTTPConfig{}
+	}
+	return d.merge(logHTTPConfigs[origin])
+}
+
+// httpClientFor returns the client for fetching from the log at logURL. It
+// is httpClient unless the log has its own HTTP settings.
+func httpClientFor(origin string, logURL *url.URL) (*http.Client, error) {
+	c := httpConfigFor(origin)
+	if c == nil {
+		return httpClient, nil
+	}
+	rt, err := c.transport(logURL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP settings for %q: %w", origin, err)
+	}
+	return &http.Client{
+		Timeout:   httpClient.Timeout,
+		Transport: newCachingTransport(newFetchTransport(rt, *httpRetries, *httpRateLimit, httpStats)),
+	}, nil
+}
+
+// plainHTTPClientFor is like httpClientFor, but without retries or
+// caching, for clients which do their own. It returns nil if the log has no
+// HTTP settings.
+func plainHTTPClientFor(origin string, logURL *url.URL) (*http.Client, error) {
+	c := httpConfigFor(origin)
+	if c == nil {
+		return nil, nil
+	}
+	rt, err := c.transport(logURL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP settings for %q: %w", origin, err)
+	}
+	return &http.Client{Timeout: httpClient.Timeout, Transport: rt}, nil
+}
+
+// transport returns a transport using the TLS and proxy settings, which
+// adds the headers and bearer token to requests for the log's host. They
+// are never sent to other hosts, e.g. after a redirect.
+func (c *logHTTPConfig) transport(logURL *url.URL) (http.RoundTripper, error) {
+	// Requests for buckets go to the bucket's endpoint.
+	logURL, err := objectURL(logURL)
+	if err != nil {
+		return nil, err
+	}
+	t := httpTransport.Clone()
+	if c.proxy != nil {
+		t.Proxy = http.ProxyURL(c.proxy)
+	}
+	if c.certFile != "" || len(c.rootCAs) > 0 {
+		tc := &tls.Config{MinVersion: tls.VersionTLS12}
+		if t.TLSClientConfig != nil {
+			tc = t.TLSClientConfig.Clone()
+		}
+		if c.certFile != "" {
+			cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("loading client certificate: %w", err)
+			}
+			tc.Certificates = []tls.Certificate{cert}
+		}
+		if len(c.rootCAs) > 0 {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			for _, path := range c.rootCAs {
+				pem, err := os.ReadFile(path)
+				if err != nil {
+					return nil, fmt.Errorf("reading root CA: %w", err)
+				}
+				if !pool.AppendCertsFromPEM(pem) {
+					return nil, fmt.Errorf("no certificates found in root CA file %s", path)
+				}
+			}
+			tc.RootCAs = pool
+		}
+		t.TLSClientConfig = tc
+	}
+
+	header := c.headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	token, err := c.bearerToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	if len(header) == 0 {
+		return t, nil
+	}
+	return &headerTransport{next: t, host: logURL.Host, header: header}, nil
+}
+
+// bearerToken reads the bearer token, if one is configured. Errors never
+// include the token.
+func (c *logHTTPConfig) bearerToken() (string, error) {
+	switch {
+	case c.tokenFile != "":
+		b, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("reading bearer token: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	case c.tokenEnv != "":
+		t := strings.TrimSpace(os.Getenv(c.tokenEnv))
+		if t == "" {
+			return "", fmt.Errorf("bearer token environment variable %s is not set", c.tokenEnv)
+		}
+		return t, nil
+	}
+	return "", nil
+}
+
+// headerTransport adds headers to requests for a single host.
+type headerTransport struct {
+	next   http.RoundTripper
+	host   string
+	header http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, vs := range t.header {
+		req.Header[name] = vs
+	}
+	return t.next.RoundTrip(req)
+}
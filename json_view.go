@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Leaf renderers selectable with the `r` key.
+const (
+	rendererAuto = "auto"
+	rendererJSON = "json"
+	rendererText = "text"
+)
+
+// nextRenderer returns the renderer that follows r in the `r` key cycle.
+func nextRenderer(r string) string {
+	switch r {
+	case rendererAuto:
+		return rendererJSON
+	case rendererJSON:
+		return rendererText
+	default:
+		return rendererAuto
+	}
+}
+
+// copyToClipboard writes s to the system clipboard, falling back to an OSC 52
+// escape sequence so that copying also works over SSH.
+var copyToClipboard = func(s string) error {
+	if err := clipboard.WriteAll(s); err == nil {
+		return nil
+	}
+	_, err := osc52.New(s).WriteTo(os.Stderr)
+	return err
+}
+
+var (
+	jsonKeyStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#A78BFA"))
+	jsonStringStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#34D399"))
+	jsonNumberStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FBBF24"))
+	jsonLitStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#2DD4BF"))
+	jsonPunctStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+	jsonCursorStyle = lipgloss.NewStyle().Background(lipgloss.Color("#312E81"))
+)
+
+type jsonKind int
+
+const (
+	jsonObject jsonKind = iota
+	jsonArray
+	jsonString
+	jsonNumber
+	jsonLiteral // true, false and null
+)
+
+// jsonNode is a parsed JSON value. Unlike encoding/json maps, object keys keep
+// the order in which they appeared in the leaf.
+type jsonNode struct {
+	kind     jsonKind
+	key      string // the object key, for members of an object
+	member   bool   // whether this value is a member of an object
+	path     string // jq-style path from the root, e.g. .spec.signature.content
+	value    string // the JSON literal for scalar values
+	children []*jsonNode
+}
+
+func (n *jsonNode) isContainer() bool {
+	return n.kind == jsonObject || n.kind == jsonArray
+}
+
+// parseJSON parses a single JSON object or array. Scalar documents are
+// rejected as they are better served by the text renderer.
+func parseJSON(data []byte) (*jsonNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := parseJSONValue(dec, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	if !root.isContainer() {
+		return nil, errors.New("JSON value is not an object or array")
+	}
+	return root, nil
+}
+
+func parseJSONValue(dec *json.Decoder, key, path string) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := &jsonNode{key: key, path: path}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			n.kind = jsonObject
+			for dec.More() {
+				kt, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				k, ok := kt.(string)
+				if !ok {
+					return nil, fmt.Errorf("unexpected object key %v", kt)
+				}
+				c, err := parseJSONValue(dec, k, jsonChildPath(path, k))
+				if err != nil {
+					return nil, err
+				}
+				c.member = true
+				n.children = append(n.children, c)
+			}
+		case '[':
+			n.kind = jsonArray
+			for i := 0; dec.More(); i++ {
+				c, err := parseJSONValue(dec, "", jsonIndexPath(path, i))
+				if err != nil {
+					return nil, err
+				}
+				n.children = append(n.children, c)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected delimiter %v", t)
+		}
+		// Consume the closing delimiter.
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.kind = jsonString
+		n.value = jsonQuote(t)
+	case json.Number:
+		n.kind = jsonNumber
+		n.value = t.String()
+	case bool:
+		n.kind = jsonLiteral
+		n.value = strconv.FormatBool(t)
+	case nil:
+		n.kind = jsonLiteral
+		n.value = "null"
+	default:
+		return nil, fmt.Errorf("unexpected token %v", tok)
+	}
+	return n, nil
+}
+
+var jsonIdentRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonChildPath returns the path of the object member key under parent.
+// Keys which are not plain identifiers use the ["key"] form.
+func jsonChildPath(parent, key string) string {
+	if jsonIdentRE.MatchString(key) {
+		return parent + "." + key
+	}
+	return jsonRoot(parent) + "[" + jsonQuote(key) + "]"
+}
+
+// jsonIndexPath returns the path of the array element i under parent.
+func jsonIndexPath(parent string, i int) string {
+	return fmt.Sprintf("%s[%d]", jsonRoot(parent), i)
+}
+
+// jsonRoot returns parent, or "." for the root, whose path is empty, since
+// jq paths start with a dot.
+func jsonRoot(parent string) string {
+	if parent == "" {
+		return "."
+	}
+	return parent
+}
+
+func jsonQuote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return strconv.Quote(s)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsonLine is a single visible line of the rendered document.
+type jsonLine struct {
+	node    *jsonNode
+	depth   int
+	closing bool // the closing bracket of an expanded container
+	last    bool // no trailing comma
+}
+
+// jsonView renders a JSON leaf with syntax highlighting and foldable
+// objects and arrays, tracking a cursor line for keyboard navigation.
+type jsonView struct {
+	root   *jsonNode
+	folded map[string]bool
+	cursor int
+	lines  []jsonLine
+}
+
+func newJSONView(root *jsonNode) *jsonView {
+	v := &jsonView{
+		root:   root,
+		folded: make(map[string]bool),
+	}
+	v.layout()
+	return v
+}
+
+func (v *jsonView) layout() {
+	v.lines = v.lines[:0]
+	var walk func(n *jsonNode, depth int, last bool)
+	walk = func(n *jsonNode, depth int, last bool) {
+		v.lines = append(v.lines, jsonLine{node: n, depth: depth, last: last})
+		if !n.isContainer() || v.folded[n.path] || len(n.children) == 0 {
+			return
+		}
+		for i, c := range n.children {
+			walk(c, depth+1, i == len(n.children)-1)
+		}
+		v.lines = append(v.lines, jsonLine{node: n, depth: depth, closing: true, last: last})
+	}
+	walk(v.root, 0, true)
+	if v.cursor >= len(v.lines) {
+		v.cursor = len(v.lines) - 1
+	}
+}
+
+// MoveCursor moves the cursor by delta lines, clamped to the document.
+func (v *jsonView) MoveCursor(delta int) {
+	v.cursor += delta
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+	if v.cursor >= len(v.lines) {
+		v.cursor = len(v.lines) - 1
+	}
+}
+
+// Cursor returns the index of the line under the cursor.
+func (v *jsonView) Cursor() int {
+	return v.cursor
+}
+
+// CurrentPath returns the path of the value under the cursor.
+func (v *jsonView) CurrentPath() string {
+	p := v.lines[v.cursor].node.path
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// ToggleFold folds or unfolds the container under the cursor. When the
+// cursor is on a scalar, its enclosing container is folded instead.
+func (v *jsonView) ToggleFold() {
+	n := v.lines[v.cursor].node
+	if !n.isContainer() {
+		n = v.parentOf(n)
+		if n == nil {
+			return
+		}
+	}
+	v.folded[n.path] = !v.folded[n.path]
+	v.layout()
+	// Keep the cursor on the line of the container that was toggled.
+	for i, l := range v.lines {
+		if l.node == n && !l.closing {
+			v.cursor = i
+			break
+		}
+	}
+}
+
+// SetAllFolded folds or unfolds every container below the root.
+func (v *jsonView) SetAllFolded(folded bool) {
+	cur := v.lines[v.cursor].node
+	v.folded = make(map[string]bool)
+	if folded {
+		var walk func(n *jsonNode)
+		walk = func(n *jsonNode) {
+			for _, c := range n.children {
+				if c.isContainer() {
+					v.folded[c.path] = true
+					walk(c)
+				}
+			}
+		}
+		walk(v.root)
+	}
+	v.layout()
+	v.cursor = 0
+	for i, l := range v.lines {
+		if l.node == cur && !l.closing {
+			v.cursor = i
+			break
+		}
+	}
+}
+
+func (v *jsonView) parentOf(target *jsonNode) *jsonNode {
+	var find func(n *jsonNode) *jsonNode
+	find = func(n *jsonNode) *jsonNode {
+		for _, c := range n.children {
+			if c == target {
+				return n
+			}
+			if p := find(c); p != nil {
+				return p
+			}
+		}
+		return nil
+	}
+	return find(v.root)
+}
+
+// Render returns the highlighted document with the cursor line marked.
+func (v *jsonView) Render() string {
+	var sb strings.Builder
+	for i, l := range v.lines {
+		line := v.renderLine(l)
+		if i == v.cursor {
+			line = jsonCursorStyle.Render(line)
+		}
+		sb.WriteString(line)
+		if i < len(v.lines)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func (v *jsonView) renderLine(l jsonLine) string {
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat("  ", l.depth))
+	n := l.node
+	open, close := "{", "}"
+	if n.kind == jsonArray {
+		open, close = "[", "]"
+	}
+	if l.closing {
+		sb.WriteString(jsonPunctStyle.Render(close))
+	} else {
+		if n.member {
+			sb.WriteString(jsonKeyStyle.Render(jsonQuote(n.key)))
+			sb.WriteString(jsonPunctStyle.Render(": "))
+		}
+		switch {
+		case n.kind == jsonString:
+			sb.WriteString(jsonStringStyle.Render(n.value))
+		case n.kind == jsonNumber:
+			sb.WriteString(jsonNumberStyle.Render(n.value))
+		case n.kind == jsonLiteral:
+			sb.WriteString(jsonLitStyle.Render(n.value))
+		case len(n.children) == 0:
+			sb.WriteString(jsonPunctStyle.Render(open + close))
+		case v.folded[n.path]:
+			unit := "keys"
+			if n.kind == jsonArray {
+				unit = "items"
+			}
+			sb.WriteString(jsonPunctStyle.Render(fmt.Sprintf("%s…%s", open, close)))
+			sb.WriteString(lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render(fmt.Sprintf(" %d %s", len(n.children), unit)))
+		default:
+			sb.WriteString(jsonPunctStyle.Render(open))
+			return sb.String()
+		}
+	}
+	if !l.last {
+		sb.WriteString(jsonPunctStyle.Render(","))
+	}
+	return sb.String()
+}
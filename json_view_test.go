@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mhutchinson/woodpecker/model"
+	"github.com/transparency-dev/formats/log"
+)
+
+func TestParseJSONPaths(t *testing.T) {
+	root, err := parseJSON([]byte(`{"spec": {"signature": {"content": "abc"}}, "a b": [1, {"c": null}]}`))
+	if err != nil {
+		t.Fatalf("parseJSON: %v", err)
+	}
+	v := newJSONView(root)
+
+	var paths []string
+	for _, l := range v.lines {
+		if !l.closing {
+			paths = append(paths, l.node.path)
+		}
+	}
+	want := []string{"", ".spec", ".spec.signature", ".spec.signature.content", `.["a b"]`, `.["a b"][0]`, `.["a b"][1]`, `.["a b"][1].c`}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("got paths %q, want %q", paths, want)
+	}
+
+	// The elements of an array at the root start with a dot too.
+	root, err = parseJSON([]byte(`[{"a": 1}]`))
+	if err != nil {
+		t.Fatalf("parseJSON: %v", err)
+	}
+	if got := root.children[0].children[0].path; got != ".[0].a" {
+		t.Errorf("got path %q in an array at the root, want %q", got, ".[0].a")
+	}
+}
+
+func TestParseJSONRejects(t *testing.T) {
+	for _, in := range []string{
+		"not json",
+		`"just a string"`,
+		"42",
+		`{"a": 1} trailing`,
+		`{"a": `,
+	} {
+		if _, err := parseJSON([]byte(in)); err == nil {
+			t.Errorf("parseJSON(%q): expected error, got nil", in)
+		}
+	}
+}
+
+func TestJSONViewFolding(t *testing.T) {
+	root, err := parseJSON([]byte(`{"a": {"b": 1, "c": 2}, "d": [true, false]}`))
+	if err != nil {
+		t.Fatalf("parseJSON: %v", err)
+	}
+	v := newJSONView(root)
+	if got, want := len(v.lines), 10; got != want {
+		t.Fatalf("expected %d lines when expanded, got %d", want, got)
+	}
+
+	// Fold .a from one of its scalar members.
+	v.MoveCursor(2)
+	if got := v.CurrentPath(); got != ".a.b" {
+		t.Fatalf("expected cursor on .a.b, got %s", got)
+	}
+	v.ToggleFold()
+	if got := v.CurrentPath(); got != ".a" {
+		t.Errorf("expected cursor on folded .a, got %s", got)
+	}
+	if got, want := len(v.lines), 7; got != want {
+		t.Errorf("expected %d lines with .a folded, got %d", want, got)
+	}
+	if !strings.Contains(v.Render(), "2 keys") {
+		t.Errorf("expected folded summary in render, got:\n%s", v.Render())
+	}
+
+	v.SetAllFolded(true)
+	if got, want := len(v.lines), 4; got != want {
+		t.Errorf("expected %d lines with all folded, got %d", want, got)
+	}
+	v.SetAllFolded(false)
+	if got, want := len(v.lines), 10; got != want {
+		t.Errorf("expected %d lines with all expanded, got %d", want, got)
+	}
+
+	v.MoveCursor(-100)
+	if got := v.CurrentPath(); got != "." {
+		t.Errorf("expected cursor clamped to root, got %s", got)
+	}
+}
+
+func TestLeafRendererSelection(t *testing.T) {
+	clients := map[string]logClient{
+		"origin": &mockLogClient{},
+	}
+	m := NewModel([]string{"origin"}, clients, mockDistributors(&mockDistributor{}), nil, "origin", modelOptions{})
+	m.checkpoint = &model.Checkpoint{Checkpoint: &log.Checkpoint{Size: 10}}
+
+	m.Update(leafMsg{leaf: model.Leaf{Index: 1, Contents: []byte(`{"spec": {"signature": {"content": "x"}}}`)}})
+	if m.jsonView == nil {
+		t.Fatal("expected JSON leaf to use the JSON renderer")
+	}
+
+	var copied string
+	oldCopy := copyToClipboard
+	defer func() { copyToClipboard = oldCopy }()
+	copyToClipboard = func(s string) error {
+		copied = s
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	if copied != ".spec.signature.content" {
+		t.Errorf("expected .spec.signature.content to be copied, got %q", copied)
+	}
+
+	// Cycling to the text renderer shows the raw leaf.
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	if m.renderer != rendererText || m.jsonView != nil {
+		t.Errorf("expected text renderer, got %q (jsonView set: %t)", m.renderer, m.jsonView != nil)
+	}
+
+	// Invalid JSON falls back to the text renderer even when JSON is forced.
+	m.renderer = rendererJSON
+	m.Update(leafMsg{leaf: model.Leaf{Index: 2, Contents: []byte("{not json")}})
+	if m.jsonView != nil {
+		t.Error("expected invalid JSON to fall back to the text renderer")
+	}
+}
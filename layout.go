@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"filippo.io/sunlight"
+	"github.com/transparency-dev/merkle/rfc6962"
+	serverless_api "github.com/transparency-dev/serverless-log/api"
+	serverless_layout "github.com/transparency-dev/serverless-log/api/layout"
+	serverless_client "github.com/transparency-dev/serverless-log/client"
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+// tileLayout describes where a type of log keeps its checkpoint, hash tiles
+// and leaves, and how to read them. Every layout has tiles of height 8,
+// whose bottom row at level L holds the nodes at level 8L of the tree.
+type tileLayout struct {
+	checkpointPath string
+	// tilePath returns the path of the hash tile at level and index, with
+	// width hashes in its bottom row.
+	tilePath func(level, index, width uint64) string
+	// tileHashes parses a hash tile, returning its bottom row.
+	tileHashes func(tile []byte, width uint64) ([][]byte, error)
+	// leafPaths returns the paths of the files holding the leaves under the
+	// level 0 tile at index, each of which holds the same number of leaves.
+	leafPaths func(index, width uint64) []string
+	// leaves parses a file of leaves, returning each leaf as the log's
+	// client returns it, and its leaf hash.
+	leaves func(b []byte) (leaves, hashes [][]byte, err error)
+}
+
+// layoutFor returns the layout of logs of the given type.
+func layoutFor(logType string) (*tileLayout, error) {
+	switch logType {
+	case "tiles":
+		return &tileLayout{
+			checkpointPath: "checkpoint",
+			tilePath: func(level, index, width uint64) string {
+				return tileSpecPath(tlog.Tile{H: 8, L: int(level), N: int64(index), W: int(width)}, "entries")
+			},
+			tileHashes: splitHashTile,
+			leafPaths: func(index, width uint64) []string {
+				return []string{tileSpecPath(tlog.Tile{H: 8, L: -1, N: int64(index), W: int(width)}, "entries")}
+			},
+			leaves: func(b []byte) ([][]byte, [][]byte, error) {
+				entries, err := parseEntryBundle(b)
+				if err != nil {
+					return nil, nil, err
+				}
+				return entries, hashLeaves(entries), nil
+			},
+		}, nil
+	case "static-ct":
+		return &tileLayout{
+			checkpointPath: "checkpoint",
+			tilePath: func(level, index, width uint64) string {
+				return sunlight.TilePath(tlog.Tile{H: 8, L: int(level), N: int64(index), W: int(width)})
+			},
+			tileHashes: splitHashTile,
+			leafPaths: func(index, width uint64) []string {
+				return []string{sunlight.TilePath(tlog.Tile{H: 8, L: -1, N: int64(index), W: int(width)})}
+			},
+			leaves: func(b []byte) ([][]byte, [][]byte, error) {
+				var leaves, hashes [][]byte
+				for len(b) > 0 {
+					e, rest, err := sunlight.ReadTileLeafMaybeArchival(b)
+					if err != nil {
+						return nil, nil, err
+					}
+					// Leaves are the entries as JSON, as staticCTLogClient
+					// returns them.
+					leaf, err := json.Marshal(e)
+					if err != nil {
+						return nil, nil, err
+					}
+					h := tlog.RecordHash(e.MerkleTreeLeaf())
+					leaves = append(leaves, leaf)
+					hashes = append(hashes, h[:])
+					b = rest
+				}
+				return leaves, hashes, nil
+			},
+		}, nil
+	case "sumdb":
+		return &tileLayout{
+			checkpointPath: "latest",
+			tilePath: func(level, index, width uint64) string {
+				return tlog.Tile{H: 8, L: int(level), N: int64(index), W: int(width)}.Path()
+			},
+			tileHashes: splitHashTile,
+			leafPaths: func(index, width uint64) []string {
+				return []string{tlog.Tile{H: 8, L: -1, N: int64(index), W: int(width)}.Path()}
+			},
+			leaves: func(b []byte) ([][]byte, [][]byte, error) {
+				records := sumDBRecords(b)
+				return records, hashLeaves(records), nil
+			},
+		}, nil
+	case "serverless":
+		return &tileLayout{
+			checkpointPath: serverless_layout.CheckpointPath,
+			tilePath: func(level, index, width uint64) string {
+				return path.Join(serverless_layout.TilePath("", level, index, width%256))
+			},
+			tileHashes: serverlessTileHashes,
+			leafPaths: func(index, width uint64) []string {
+				paths := make([]string, width)
+				for i := range paths {
+					paths[i] = path.Join(serverless_layout.SeqPath("", index*256+uint64(i)))
+				}
+				return paths
+			},
+			leaves: func(b []byte) ([][]byte, [][]byte, error) {
+				return [][]byte{b}, hashLeaves([][]byte{b}), nil
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("logs of type %q have no tile layout", logType)
+}
+
+// tileReader reads the hash tiles of a log in a layout whose tiles hold
+// only their bottom row, for tlog.TileHashReader.
+type tileReader struct {
+	layout *tileLayout
+	fetch  serverless_client.Fetcher
+}
+
+// fetchPartial fetches the file at partial, which holds the first width
+// entries of the full file at full. Logs may delete a partial tile or
+// bundle once the full one exists, so if the partial file is missing the
+// full one is fetched instead.
+func (r tileReader) fetchPartial(ctx context.Context, partial, full string, width uint64) ([]byte, bool, error) {
+	b, err := r.fetch(ctx, partial)
+	if width == 256 || !errors.Is(err, os.ErrNotExist) {
+		return b, false, err
+	}
+	b, err = r.fetch(ctx, full)
+	return b, true, err
+}
+
+func (r tileReader) Height() int {
+	return 8
+}
+
+func (r tileReader) ReadTiles(tiles []tlog.Tile) ([][]byte, error) {
+	var data [][]byte
+	for _, t := range tiles {
+		if t.L < 0 {
+			return nil, fmt.Errorf("unexpected data tile request in ReadTiles: %v", t)
+		}
+		level, index, width := uint64(t.L), uint64(t.N), uint64(t.W)
+		b, _, err := r.fetchPartial(context.Background(), r.layout.tilePath(level, index, width), r.layout.tilePath(level, index, 256), width)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(b)) < width*32 {
+			return nil, fmt.Errorf("tile %s has %d bytes, want %d hashes", r.layout.tilePath(level, index, width), len(b), width)
+		}
+		data = append(data, b[:width*32])
+	}
+	return data, nil
+}
+
+func (r tileReader) SaveTiles(tiles []tlog.Tile, data [][]byte) {
+	// no-op
+}
+
+// tileWidth returns the width of the tile at level and index in a tree of
+// the given size, which is 256 for full tiles.
+func tileWidth(size, level, index uint64) (uint64, error) {
+	n := size >> (8 * level)
+	if index > n/256 || (index == n/256 && n%256 == 0) {
+		return 0, fmt.Errorf("tree of size %d has no tile at level %d index %d", size, level, index)
+	}
+	return min(256, n-index*256), nil
+}
+
+// tileSpecPath returns the path of a tile in the layout of
+// c2sp.org/tlog-tiles, where data tiles are in the directory dataDir.
+func tileSpecPath(t tlog.Tile, dataDir string) string {
+	p := strings.TrimPrefix(t.Path(), "tile/8/")
+	if t.L == -1 {
+		p = dataDir + strings.TrimPrefix(p, "data")
+	}
+	return "tile/" + p
+}
+
+func splitHashTile(tile []byte, width uint64) ([][]byte, error) {
+	if uint64(len(tile)) != width*32 {
+		return nil, fmt.Errorf("hash tile has %d bytes, want %d hashes", len(tile), width)
+	}
+	hashes := make([][]byte, width)
+	for i := range hashes {
+		hashes[i] = tile[i*32 : (i+1)*32]
+	}
+	return hashes, nil
+}
+
+// serverlessTileHashes parses a serverless tile, which holds every node of
+// the tile's subtrees, checking that the nodes above the bottom row are the
+// hashes of their children.
+func serverlessTileHashes(b []byte, width uint64) ([][]byte, error) {
+	var tile serverless_api.Tile
+	if err := tile.UnmarshalText(b); err != nil {
+		return nil, err
+	}
+	if uint64(tile.NumLeaves) != width {
+		return nil, fmt.Errorf("tile has %d leaves, want %d", tile.NumLeaves, width)
+	}
+	node := func(level uint, index uint64) []byte {
+		if k := serverless_api.TileNodeKey(level, index); k < uint(len(tile.Nodes)) {
+			return tile.Nodes[k]
+		}
+		return nil
+	}
+	hashes := make([][]byte, width)
+	for i := range hashes {
+		if hashes[i] = node(0, uint64(i)); len(hashes[i]) != 32 {
+			return nil, fmt.Errorf("tile is missing leaf %d", i)
+		}
+	}
+	for level := uint(1); level < 8; level++ {
+		for i := uint64(0); (i+1)<<level <= width; i++ {
+			h := node(level, i)
+			if len(h) == 0 {
+				continue
+			}
+			if !bytes.Equal(h, rfc6962.DefaultHasher.HashChildren(node(level-1, 2*i), node(level-1, 2*i+1))) {
+				return nil, fmt.Errorf("tile node %d at level %d is not the hash of its children", i, level)
+			}
+		}
+	}
+	return hashes, nil
+}
+
+func hashLeaves(leaves [][]byte) [][]byte {
+	hashes := make([][]byte, len(leaves))
+	for i, l := range leaves {
+		hashes[i] = rfc6962.DefaultHasher.HashLeaf(l)
+	}
+	return hashes
+}
@@ -17,18 +17,17 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"filippo.io/sunlight"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/mhutchinson/woodpecker/model"
-	distclient "github.com/transparency-dev/distributor/client"
 	"github.com/transparency-dev/formats/log"
 	tnote "github.com/transparency-dev/formats/note"
 	"github.com/transparency-dev/merkle/proof"
 	"github.com/transparency-dev/merkle/rfc6962"
 	serverless_client "github.com/transparency-dev/serverless-log/client"
-	tiles_client "github.com/transparency-dev/trillian-tessera/client"
 	"golang.org/x/mod/sumdb/note"
 	"golang.org/x/mod/sumdb/tlog"
 	"golang.org/x/sync/singleflight"
@@ -41,7 +40,8 @@ var clients []logClient
 
 var (
 	httpClient = &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: newCachingTransport(newFetchTransport(httpTransport, defaultHTTPRetries, 0, httpStats)),
 	}
 )
 
@@ -50,9 +50,31 @@ var (
 	customLogUrl    = flag.String("custom_log_url", "", "The base URL of a custom log to register")
 	customLogOrigin = flag.String("custom_log_origin", "", "The origin of a custom log to register")
 	customLogVKey   = flag.String("custom_log_vkey", "", "The verifier key of a custom log to register")
-	customLogType   = flag.String("custom_log_type", "", "The type of the custom log specified by the other custom_* flags. Must be empty, or one of {tiles, serverless, static-ct}.")
+	customLogType   = flag.String("custom_log_type", "", "The type of the custom log specified by the other custom_* flags. Must be empty, or one of {tiles, serverless, sumdb, static-ct, rfc6962, sigsum}.")
+
+	distributorURLs       = flag.String("distributor_url", distURL, "Comma separated list of base URLs of distributors to fetch witnessed checkpoints from")
+	staleWitnessThreshold = flag.Duration("stale_witness_threshold", defaultStaleWitnessThreshold, "Cosignatures older than this are highlighted as stale")
+	pinnedWitnessFile     = flag.String("witnesses", "", "A file of witness keys to trust, in the witness policy format. The quorum line is optional.")
+	distributorWitnesses  = flag.String("distributor_witnesses", witnessesUnion, "How to combine the --witnesses with those advertised by the distributors. One of {union, intersect, ignore}.")
+	httpTimeout           = flag.Duration("http_timeout", 30*time.Second, "Timeout for an HTTP request, including any retries")
+	httpResponseTimeout   = flag.Duration("http_response_timeout", 10*time.Second, "Timeout for each HTTP attempt to receive the response headers")
+	httpRetries           = flag.Int("http_retries", defaultHTTPRetries, "How many times to retry HTTP requests that fail with 429 or 5xx")
+	httpRateLimit         = flag.Float64("http_rate_limit", 0, "The most HTTP requests per second to make to each host, or 0 for no limit")
+	gcsEndpoint           = flag.String("gcs_endpoint", "https://storage.googleapis.com", "The endpoint used to read gs:// URLs")
+	s3Endpoint            = flag.String("s3_endpoint", "https://s3.amazonaws.com", "The endpoint used to read s3:// URLs, in path style")
+	logHTTPConfigFile     = flag.String("log_http_config", "", "A file of per-log HTTP settings: headers, bearer tokens, client certificates, root CAs and proxies")
+	exportFormat          = flag.String("export_format", exportJSONL, "The format of leaves written by the export command. One of {jsonl, csv, files}.")
+	noDistributor         = flag.Bool("no_distributor", false, "Disable fetching witnessed checkpoints from the distributor, e.g. when working offline")
+
+	witnessPolicyFiles = policyFlag{}
+	refreshIntervals   = refreshFlag{}
 )
 
+func init() {
+	flag.Var(witnessPolicyFiles, "witness_policy", "A witness policy file in the Sigsum policy format. Either FILE, to use for all logs, or ORIGIN=FILE to use for a single log. May be repeated.")
+	flag.Var(refreshIntervals, "refresh_interval", "How often to fetch the checkpoint, default 5s. Either DURATION, to use for all logs, or ORIGIN=DURATION to use for a single log. May be repeated.")
+}
+
 func initLogging() (*os.File, error) {
 	// Check if user explicitly requested logging to stderr
 	logToStderr := false
@@ -90,6 +112,12 @@ func initLogging() (*os.File, error) {
 
 func main() {
 	flag.Parse()
+	// Commands come after the flags, and may be followed by more flags.
+	var command string
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
+		_ = flag.CommandLine.Parse(flag.Args()[1:])
+	}
 	if lf, err := initLogging(); err == nil && lf != nil {
 		defer func() {
 			if err := lf.Close(); err != nil {
@@ -99,6 +127,20 @@ func main() {
 	}
 	defer klog.Flush()
 
+	httpTransport.ResponseHeaderTimeout = *httpResponseTimeout
+	httpClient.Timeout = *httpTimeout
+	httpClient.Transport = newCachingTransport(newFetchTransport(httpTransport, *httpRetries, *httpRateLimit, httpStats))
+	if *logHTTPConfigFile != "" {
+		var err error
+		if logHTTPConfigs, err = loadHTTPConfig(*logHTTPConfigFile); err != nil {
+			klog.Exitf("Failed to load HTTP settings: %v", err)
+		}
+		for o, c := range logHTTPConfigs {
+			// String never includes header values or tokens.
+			klog.Infof("HTTP settings for %q: %v", o, c)
+		}
+	}
+
 	// Initialize built-in clients
 	builtInClients := []struct {
 		url, origin, vkey string
@@ -154,6 +196,10 @@ func main() {
 			client, err = newTLogTilesLogClient(c.url, c.origin, c.vkey)
 		case "static-ct":
 			client, err = newStaticCTLogClient(c.url, c.origin, c.vkey)
+		case "rfc6962":
+			client, err = newRFC6962LogClient(c.url, c.origin, c.vkey)
+		case "sigsum":
+			client, err = newSigsumLogClient(c.url, c.origin, c.vkey)
 		}
 		if err != nil {
 			panic(fmt.Sprintf("Failed to initialize built-in client for %s: %v", c.origin, err))
@@ -161,29 +207,37 @@ func main() {
 		clients = append(clients, client)
 	}
 
-	switch *customLogType {
-	case "":
-		break
-	case "tiles":
-		c, err := newTLogTilesLogClient(*customLogUrl, *customLogOrigin, *customLogVKey)
-		if err != nil {
-			klog.Exitf("Failed to initialize custom tiles log: %v", err)
+	if *customLogType != "" {
+		var c logClient
+		var err error
+		switch *customLogType {
+		case "tiles":
+			c, err = newTLogTilesLogClient(*customLogUrl, *customLogOrigin, *customLogVKey)
+		case "serverless":
+			c, err = newServerlessLogClient(*customLogUrl, *customLogOrigin, *customLogVKey)
+		case "sumdb":
+			c, err = newSumDBLogClient(*customLogUrl, *customLogOrigin, *customLogVKey)
+		case "static-ct":
+			c, err = newStaticCTLogClient(*customLogUrl, *customLogOrigin, *customLogVKey)
+		case "rfc6962":
+			c, err = newRFC6962LogClient(*customLogUrl, *customLogOrigin, *customLogVKey)
+		case "sigsum":
+			c, err = newSigsumLogClient(*customLogUrl, *customLogOrigin, *customLogVKey)
+		default:
+			klog.Exitf("custom_log_type %s not recognised", *customLogType)
 		}
-		clients = append([]logClient{c}, clients...)
-	case "serverless":
-		c, err := newServerlessLogClient(*customLogUrl, *customLogOrigin, *customLogVKey)
 		if err != nil {
-			klog.Exitf("Failed to initialize custom serverless log: %v", err)
+			klog.Exitf("Failed to initialize custom %s log: %v", *customLogType, err)
 		}
-		clients = append([]logClient{c}, clients...)
-	case "static-ct":
-		c, err := newStaticCTLogClient(*customLogUrl, *customLogOrigin, *customLogVKey)
-		if err != nil {
-			klog.Exitf("Failed to initialize custom static-ct log: %v", err)
+		// A custom log replaces a built-in log with the same origin, e.g. to
+		// open a mirror of it.
+		others := make([]logClient, 0, len(clients))
+		for _, o := range clients {
+			if o.GetOrigin() != c.GetOrigin() {
+				others = append(others, o)
+			}
 		}
-		clients = append([]logClient{c}, clients...)
-	default:
-		klog.Exitf("custom_log_type %s not recognised", *customLogType)
+		clients = append([]logClient{c}, others...)
 	}
 	logClients := make(map[string]logClient, len(clients))
 	logOrigins := make([]string, 0, len(clients))
@@ -192,20 +246,79 @@ func main() {
 		logOrigins = append(logOrigins, c.GetOrigin())
 	}
 
-	dist := distclient.NewRestDistributor(distURL, httpClient)
-	witKeys, err := dist.GetWitnesses()
+	// The witness keys are fetched by the model in the background, so that
+	// logs can be browsed without access to the distributor.
+	var dists []distributor
+	if !*noDistributor {
+		var err error
+		if dists, err = newDistributors(*distributorURLs); err != nil {
+			klog.Exitf("Failed to configure distributors: %v", err)
+		}
+	}
+
+	policies, err := loadWitnessPolicies(witnessPolicyFiles)
 	if err != nil {
-		panic(fmt.Sprintf("Witnesses not available: %v", err))
+		klog.Exitf("Failed to load witness policy: %v", err)
 	}
-	witVerifiers := make([]note.Verifier, 0, len(witKeys))
-	for _, k := range witKeys {
-		v, err := tnote.NewVerifierForCosignatureV1(k)
-		if err != nil {
-			panic(fmt.Sprintf("Invalid witness key: %v", err))
+	for o := range policies {
+		if _, ok := logClients[o]; o != "" && !ok {
+			klog.Exitf("Witness policy given for unknown log %q; use ORIGIN=FILE for a single log, or ./FILE for a file whose name has '='", o)
+		}
+	}
+	// Sigsum tree heads identify the witnesses that cosigned them only by
+	// the hash of their key, so their cosignatures are named and checked
+	// using the witnesses in the log's policy.
+	for _, c := range clients {
+		if s, ok := c.(*sigsumLogClient); ok {
+			p, ok := policies[s.GetOrigin()]
+			if !ok {
+				p = policies[""]
+			}
+			s.useWitnessPolicy(p)
+		}
+	}
+
+	for o := range logHTTPConfigs {
+		if _, ok := logClients[o]; o != "" && !ok {
+			klog.Exitf("HTTP settings given for unknown log %q", o)
+		}
+	}
+	for o := range refreshIntervals {
+		if _, ok := logClients[o]; o != "" && !ok {
+			klog.Exitf("Refresh interval given for unknown log %q", o)
 		}
-		witVerifiers = append(witVerifiers, v)
 	}
 
+	switch command {
+	case "":
+	case "mirror":
+		runMirror(flag.Args())
+		return
+	case "export":
+		runExport(flag.Args())
+		return
+	case "audit":
+		runAudit(flag.Args())
+		return
+	default:
+		klog.Exitf("Unknown command %q", command)
+	}
+
+	switch *distributorWitnesses {
+	case witnessesUnion, witnessesIntersect, witnessesIgnore:
+	default:
+		klog.Exitf("distributor_witnesses %s not recognised", *distributorWitnesses)
+	}
+	var pinned []note.Verifier
+	if *pinnedWitnessFile != "" {
+		if pinned, err = loadPinnedWitnesses(*pinnedWitnessFile); err != nil {
+			klog.Exitf("Failed to load pinned witnesses: %v", err)
+		}
+	}
+	// Until the distributors have been asked, trust the pinned witnesses
+	// unless only those also advertised by the distributors are wanted.
+	witVerifiers, _, _ := combineWitnesses(*distributorWitnesses, pinned, nil)
+
 	initialLog := clients[0].GetOrigin()
 	if len(*origin) > 0 {
 		for _, c := range clients {
@@ -216,118 +329,87 @@ func main() {
 		}
 	}
 
-	pModel := NewModel(logOrigins, logClients, dist, witVerifiers, initialLog)
+	pModel := NewModel(logOrigins, logClients, dists, witVerifiers, initialLog, modelOptions{
+		pinnedWitnesses:       pinned,
+		witnessMode:           *distributorWitnesses,
+		witnessPolicies:       policies,
+		staleWitnessThreshold: *staleWitnessThreshold,
+		refreshIntervals:      refreshIntervals,
+	})
 	p := tea.NewProgram(pModel, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		panic(err)
 	}
 }
 
+// commandClient returns the log that commands act on: the log chosen with
+// --origin, or else the custom log or first built-in log.
+func commandClient() logClient {
+	if *origin == "" {
+		return clients[0]
+	}
+	for _, c := range clients {
+		if c.GetOrigin() == *origin {
+			return c
+		}
+	}
+	klog.Exitf("Unknown log %q", *origin)
+	return nil
+}
+
 type logClient interface {
 	GetOrigin() string
 	GetVerifier() note.Verifier
 	GetCheckpoint() (*model.Checkpoint, error)
-	GetLeaf(checkpoint *model.Checkpoint, index uint64) ([]byte, error)
+	// GetLeaf fetches the leaf at index and proves its inclusion in the
+	// checkpoint. If the leaf was fetched but could not be verified, the
+	// unverified leaf is returned along with the error.
+	GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error)
 	FormatLeaf(leaf []byte) string
 	GetLogType() string
 	GetURL() string
 }
 
-func newTLogTilesLogClient(lr string, origin string, vkey string) (logClient, error) {
-	if !strings.HasSuffix(lr, "/") {
-		lr = lr + "/"
-	}
-	logRoot, err := url.Parse(lr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse URL %q: %w", lr, err)
-	}
-	fetcher := newFetcher(logRoot)
-	verifier, err := note.NewVerifier(vkey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create verifier: %w", err)
-	}
-	if len(origin) == 0 {
-		origin = verifier.Name()
-		klog.Infof("No origin provided; using verifier name: %q", origin)
-	}
-	return &tLogTilesLogClient{
-		url:      lr,
-		origin:   origin,
-		verifier: verifier,
-		fetcher: func(ctx context.Context, path string) ([]byte, error) {
-			return fetcher(ctx, path)
-		},
-	}, nil
-}
-
-type tLogTilesLogClient struct {
-	url      string
-	origin   string
-	verifier note.Verifier
-	fetcher  tiles_client.Fetcher
+// verifyLeaf checks the inclusion proof p of leaf against the checkpoint,
+// returning the leaf along with its proof. The leaf is returned even if the
+// proof does not verify, so that callers can show what the log served.
+func verifyLeaf(checkpoint *model.Checkpoint, leaf []byte, p *model.InclusionProof) (*model.Leaf, error) {
+	l := &model.Leaf{
+		Contents: leaf,
+		Index:    p.Index,
+		LeafHash: p.LeafHash,
+		Proof:    p,
+	}
+	if err := proof.VerifyInclusion(rfc6962.DefaultHasher, p.Index, checkpoint.Size, p.LeafHash, p.Hashes, checkpoint.Hash); err != nil {
+		return l, fmt.Errorf("failed to verify inclusion proof: %w", err)
+	}
+	l.Verified = true
+	return l, nil
 }
 
-func (c *tLogTilesLogClient) GetLogType() string {
-	return "tiles"
-}
-
-func (c *tLogTilesLogClient) GetURL() string {
-	return c.url
-}
-
-func (c *tLogTilesLogClient) GetOrigin() string {
-	return c.origin
-}
-
-func (c *tLogTilesLogClient) GetVerifier() note.Verifier {
-	return c.verifier
-}
-
-func (c *tLogTilesLogClient) GetCheckpoint() (*model.Checkpoint, error) {
-	cp, raw, n, err := tiles_client.FetchCheckpoint(context.Background(), c.fetcher, c.verifier, c.origin)
-	return &model.Checkpoint{
-		Checkpoint: cp,
-		Raw:        raw,
-		Note:       n,
-	}, err
+// bundleCache holds the most recently fetched bundle of leaves of a log, so
+// that consecutive leaves are read with a single fetch.
+type bundleCache struct {
+	mu     sync.Mutex
+	index  uint64
+	width  uint64
+	leaves [][]byte
 }
 
-func (c *tLogTilesLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) ([]byte, error) {
-	if checkpoint == nil {
-		return nil, errors.New("checkpoint is nil")
+// get returns the leaves of the bundle at index, which holds width leaves,
+// calling fetch if it isn't cached.
+func (c *bundleCache) get(index, width uint64, fetch func() ([][]byte, error)) ([][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.leaves != nil && c.index == index && c.width == width {
+		return c.leaves, nil
 	}
-	if index >= checkpoint.Size {
-		return nil, fmt.Errorf("index %d out of bounds for checkpoint size %d", index, checkpoint.Size)
-	}
-	bundleIndex := index / 256
-	leafOffset := index % 256
-	// TODO(mhutchinson): cache the bundle so consecutive leaf fetching is efficient
-	bundle, err := tiles_client.GetEntryBundle(context.Background(), c.fetcher, bundleIndex, checkpoint.Size)
+	leaves, err := fetch()
 	if err != nil {
 		return nil, err
 	}
-	leaf := bundle.Entries[leafOffset]
-
-	pb, err := tiles_client.NewProofBuilder(context.Background(), *checkpoint.Checkpoint, c.fetcher)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create proof builder: %w", err)
-	}
-	incProof, err := pb.InclusionProof(context.Background(), index)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build inclusion proof: %w", err)
-	}
-
-	h := rfc6962.DefaultHasher
-	leafHash := h.HashLeaf(leaf)
-	if err := proof.VerifyInclusion(h, index, checkpoint.Size, leafHash, incProof, checkpoint.Hash); err != nil {
-		return nil, fmt.Errorf("failed to verify inclusion proof: %w", err)
-	}
-
-	return leaf, nil
-}
-
-func (c *tLogTilesLogClient) FormatLeaf(leaf []byte) string {
-	return string(leaf)
+	c.index, c.width, c.leaves = index, width, leaves
+	return leaves, nil
 }
 
 func newServerlessLogClient(lr string, origin string, vkey string) (logClient, error) {
@@ -338,7 +420,10 @@ func newServerlessLogClient(lr string, origin string, vkey string) (logClient, e
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL %q: %w", lr, err)
 	}
-	fetcher := newFetcher(logRoot)
+	fetcher, err := newLogFetcher(origin, logRoot)
+	if err != nil {
+		return nil, err
+	}
 	verifier, err := note.NewVerifier(vkey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create verifier: %w", err)
@@ -383,7 +468,7 @@ func (c *serverlessLogClient) GetCheckpoint() (*model.Checkpoint, error) {
 	}, err
 }
 
-func (c *serverlessLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) ([]byte, error) {
+func (c *serverlessLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error) {
 	if checkpoint == nil {
 		return nil, errors.New("checkpoint is nil")
 	}
@@ -395,6 +480,14 @@ func (c *serverlessLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64
 		return nil, err
 	}
 
+	p, err := c.inclusionProof(checkpoint, index, leaf)
+	if err != nil {
+		return &model.Leaf{Contents: leaf, Index: index, LeafHash: rfc6962.DefaultHasher.HashLeaf(leaf)}, err
+	}
+	return verifyLeaf(checkpoint, leaf, p)
+}
+
+func (c *serverlessLogClient) inclusionProof(checkpoint *model.Checkpoint, index uint64, leaf []byte) (*model.InclusionProof, error) {
 	h := rfc6962.DefaultHasher
 	pb, err := serverless_client.NewProofBuilder(context.Background(), *checkpoint.Checkpoint, h.HashChildren, c.fetcher)
 	if err != nil {
@@ -404,13 +497,12 @@ func (c *serverlessLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64
 	if err != nil {
 		return nil, fmt.Errorf("failed to build inclusion proof: %w", err)
 	}
-
-	leafHash := h.HashLeaf(leaf)
-	if err := proof.VerifyInclusion(h, index, checkpoint.Size, leafHash, incProof, checkpoint.Hash); err != nil {
-		return nil, fmt.Errorf("failed to verify inclusion proof: %w", err)
-	}
-
-	return leaf, nil
+	return &model.InclusionProof{
+		Index:    index,
+		TreeSize: checkpoint.Size,
+		LeafHash: h.HashLeaf(leaf),
+		Hashes:   incProof,
+	}, nil
 }
 
 func (c *serverlessLogClient) FormatLeaf(leaf []byte) string {
@@ -418,11 +510,17 @@ func (c *serverlessLogClient) FormatLeaf(leaf []byte) string {
 }
 
 func newSumDBLogClient(lr string, origin string, vkey string) (logClient, error) {
+	if !strings.HasSuffix(lr, "/") {
+		lr = lr + "/"
+	}
 	logRoot, err := url.Parse(lr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL %q: %w", lr, err)
 	}
-	fetcher := newFetcher(logRoot)
+	fetcher, err := newLogFetcher(origin, logRoot)
+	if err != nil {
+		return nil, err
+	}
 	verifier, err := note.NewVerifier(vkey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create verifier: %w", err)
@@ -440,6 +538,7 @@ type sumDBLogClient struct {
 	origin   string
 	verifier note.Verifier
 	fetcher  serverless_client.Fetcher
+	bundles  bundleCache
 }
 
 func (c *sumDBLogClient) GetLogType() string {
@@ -459,7 +558,7 @@ func (c *sumDBLogClient) GetVerifier() note.Verifier {
 }
 
 func (c *sumDBLogClient) GetCheckpoint() (*model.Checkpoint, error) {
-	cpRaw, err := c.fetcher(context.Background(), "/latest")
+	cpRaw, err := c.fetcher(context.Background(), "latest")
 	if err != nil {
 		return nil, err
 	}
@@ -476,14 +575,16 @@ func (c *sumDBLogClient) Height() int {
 	return 8
 }
 
+// sumDBLayout is the layout of sumdb logs.
+var sumDBLayout, _ = layoutFor("sumdb")
+
 func (c *sumDBLogClient) ReadTiles(tiles []tlog.Tile) ([][]byte, error) {
 	var data [][]byte
 	for _, t := range tiles {
 		if t.L < 0 {
 			return nil, fmt.Errorf("unexpected data tile request in ReadTiles: %v", t)
 		}
-		path := "/" + t.Path()
-		b, err := c.fetcher(context.Background(), path)
+		b, err := c.fetcher(context.Background(), t.Path())
 		if err != nil {
 			return nil, err
 		}
@@ -496,72 +597,135 @@ func (c *sumDBLogClient) SaveTiles(tiles []tlog.Tile, data [][]byte) {
 	// no-op
 }
 
-func (c *sumDBLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) ([]byte, error) {
+func (c *sumDBLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error) {
 	if checkpoint == nil {
 		return nil, errors.New("checkpoint is nil")
 	}
 	if index >= checkpoint.Size {
 		return nil, fmt.Errorf("index %d out of bounds for checkpoint size %d", index, checkpoint.Size)
 	}
-	const pathBase = 1000
-	offset := index / 256
-	nStr := fmt.Sprintf("%03d", offset%pathBase)
-	for offset >= pathBase {
-		offset /= pathBase
-		nStr = fmt.Sprintf("x%03d/%s", offset%pathBase, nStr)
-	}
-	path := fmt.Sprintf("/tile/8/data/%s", nStr)
-	if rem := index % 256; rem != 255 {
-		path = fmt.Sprintf("%s.p/%d", path, rem+1)
-	}
-	data, err := c.fetcher(context.Background(), path)
+	// The data tile is the one for this checkpoint, so that it can also be
+	// read from a mirror of the log.
+	n, width := index/256, min(256, checkpoint.Size-index/256*256)
+	leaves, err := c.bundles.get(n, width, func() ([][]byte, error) {
+		data, err := c.fetcher(context.Background(), sumDBLayout.leafPaths(n, width)[0])
+		if err != nil {
+			return nil, err
+		}
+		leaves, _, err := sumDBLayout.leaves(data)
+		return leaves, err
+	})
 	if err != nil {
 		return nil, err
 	}
-	dataToLeaves := func(data []byte) [][]byte {
-		result := make([][]byte, 0)
-		start := 0
-		for {
-			i := bytes.Index(data[start:], []byte("\n\n"))
-			if i == -1 {
-				break
-			}
-			result = append(result, data[start:start+i+1])
-			start += i + 2
-		}
-		result = append(result, data[start:])
-		return result
-	}
-	leaves := dataToLeaves(data)
 	leafOffset := index % 256
 	if len(leaves) <= int(leafOffset) {
 		return nil, fmt.Errorf("tile data truncated: expected at least %d leaves, got %d", leafOffset+1, len(leaves))
 	}
 	leaf := leaves[leafOffset]
 
+	p, err := c.inclusionProof(checkpoint, index, leaf)
+	if err != nil {
+		return &model.Leaf{Contents: leaf, Index: index, LeafHash: rfc6962.DefaultHasher.HashLeaf(leaf)}, err
+	}
+	return verifyLeaf(checkpoint, leaf, p)
+}
+
+// sumDBRecords splits a data tile into its records, which are separated by
+// blank lines.
+func sumDBRecords(data []byte) [][]byte {
+	result := make([][]byte, 0)
+	start := 0
+	for {
+		i := bytes.Index(data[start:], []byte("\n\n"))
+		if i == -1 {
+			break
+		}
+		result = append(result, data[start:start+i+1])
+		start += i + 2
+	}
+	result = append(result, data[start:])
+	return result
+}
+
+func (c *sumDBLogClient) inclusionProof(checkpoint *model.Checkpoint, index uint64, leaf []byte) (*model.InclusionProof, error) {
 	var th tlog.Hash
 	copy(th[:], checkpoint.Hash)
 	tree := tlog.Tree{N: int64(checkpoint.Size), Hash: th}
 	hr := tlog.TileHashReader(tree, c)
-	proof, err := tlog.ProveRecord(tree.N, int64(index), hr)
+	rp, err := tlog.ProveRecord(tree.N, int64(index), hr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prove record: %w", err)
 	}
-
 	leafHash := tlog.RecordHash(leaf)
-	if err := tlog.CheckRecord(proof, tree.N, tree.Hash, int64(index), leafHash); err != nil {
-		return nil, fmt.Errorf("failed to check record: %w", err)
-	}
+	return &model.InclusionProof{
+		Index:    index,
+		TreeSize: checkpoint.Size,
+		LeafHash: leafHash[:],
+		Hashes:   recordProofHashes(rp),
+	}, nil
+}
 
-	return leaf, nil
+// recordProofHashes converts a tlog proof into the representation used by
+// the transparency-dev/merkle proof package. Both are ordered from the leaf
+// towards the root.
+func recordProofHashes(rp tlog.RecordProof) [][]byte {
+	hashes := make([][]byte, len(rp))
+	for i := range rp {
+		hashes[i] = rp[i][:]
+	}
+	return hashes
 }
 
 func (c *sumDBLogClient) FormatLeaf(leaf []byte) string {
 	return string(leaf)
 }
 
+// LeafFields returns the names of the fields of a go.sum record that are
+// exported as CSV.
+func (c *sumDBLogClient) LeafFields() []string {
+	return []string{"module", "version", "hash", "go_mod_hash"}
+}
+
+// LeafValues returns the LeafFields of the module in a record, which holds
+// its go.sum lines for the module and for its go.mod file.
+func (c *sumDBLogClient) LeafValues(leaf []byte) ([]string, error) {
+	var mod, version, hash, goModHash string
+	for _, line := range strings.Split(strings.TrimSpace(string(leaf)), "\n") {
+		f := strings.Fields(line)
+		if len(f) != 3 {
+			return nil, fmt.Errorf("invalid go.sum line %q", line)
+		}
+		v, isGoMod := strings.CutSuffix(f[1], "/go.mod")
+		if mod != "" && (f[0] != mod || v != version) {
+			return nil, fmt.Errorf("record has lines for %s %s and %s %s", mod, version, f[0], v)
+		}
+		mod, version = f[0], v
+		if isGoMod {
+			goModHash = f[2]
+		} else {
+			hash = f[2]
+		}
+	}
+	if mod == "" {
+		return nil, errors.New("empty record")
+	}
+	return []string{mod, version, hash, goModHash}, nil
+}
+
+// newLogFetcher creates a Fetcher for the log at the given root location,
+// using the log's HTTP settings.
+func newLogFetcher(origin string, root *url.URL) (serverless_client.Fetcher, error) {
+	hc, err := httpClientFor(origin, root)
+	if err != nil {
+		return nil, err
+	}
+	return newFetcher(root, hc), nil
+}
+
 // newFetcher creates a Fetcher for the log at the given root location.
-func newFetcher(root *url.URL) serverless_client.Fetcher {
+// HTTP requests are made with hc.
+func newFetcher(root *url.URL, hc *http.Client) serverless_client.Fetcher {
 	get := getByScheme[root.Scheme]
 	if get == nil {
 		panic(fmt.Errorf("unsupported URL scheme %s", root.Scheme))
@@ -572,27 +736,36 @@ func newFetcher(root *url.URL) serverless_client.Fetcher {
 		if err != nil {
 			return nil, err
 		}
-		return get(ctx, u)
+		return get(ctx, hc, u)
 	}
 }
 
-var getByScheme = map[string]func(context.Context, *url.URL) ([]byte, error){
+var getByScheme = map[string]func(context.Context, *http.Client, *url.URL) ([]byte, error){
 	"http":  readHTTP,
 	"https": readHTTP,
-	"file": func(_ context.Context, u *url.URL) ([]byte, error) {
+	"file": func(_ context.Context, _ *http.Client, u *url.URL) ([]byte, error) {
 		return os.ReadFile(u.Path)
 	},
+	"gs":       readObject,
+	"s3":       readObject,
+	"tar+file": readArchive,
+	"zip+file": readArchive,
 }
 
-func readHTTP(ctx context.Context, u *url.URL) ([]byte, error) {
+func readHTTP(ctx context.Context, hc *http.Client, u *url.URL) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := httpClient.Do(req)
+	resp, err := hc.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			klog.Errorf("resp.Body.Close(): %v", err)
+		}
+	}()
 	switch resp.StatusCode {
 	case 404:
 		klog.Infof("Not found: %q", u.String())
@@ -600,13 +773,10 @@ func readHTTP(ctx context.Context, u *url.URL) ([]byte, error) {
 	case 200:
 		break
 	default:
-		return nil, fmt.Errorf("unexpected http status %q", resp.Status)
+		// Include the start of the body, which often says what went wrong.
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return nil, fmt.Errorf("unexpected http status %q from %s: %s", resp.Status, u.Redacted(), bytes.TrimSpace(body))
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			klog.Errorf("resp.Body.Close(): %v", err)
-		}
-	}()
 	return io.ReadAll(resp.Body)
 }
 
@@ -690,9 +860,47 @@ func newStaticCTLogClient(lr string, origin string, vkey string) (logClient, err
 		return nil, fmt.Errorf("failed to extract public key: %w", err)
 	}
 
+	logRoot, err := url.Parse(lr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL %q: %w", lr, err)
+	}
+	// Leaves are read from the data tiles directly, so that a leaf whose
+	// proof doesn't verify can still be shown.
+	fetcher, err := newLogFetcher(origin, logRoot)
+	if err != nil {
+		return nil, err
+	}
+	// The sunlight client retries by itself, so it is only given a client
+	// if the log has its own HTTP settings.
+	hc, err := plainHTTPClientFor(origin, logRoot)
+	if err != nil {
+		return nil, err
+	}
+	prefix := lr
+	switch logRoot.Scheme {
+	case "gs", "s3":
+		o, err := objectURL(logRoot)
+		if err != nil {
+			return nil, err
+		}
+		prefix = o.String() + "/"
+	case "tar+file", "zip+file":
+		// Serve the snapshot to the sunlight client over HTTP.
+		archivePath, _, err := splitArchivePath(logRoot.Path)
+		if err != nil {
+			return nil, err
+		}
+		a, err := openArchive(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		prefix = "http://archive.invalid/"
+		hc = &http.Client{Transport: &archiveTransport{a: a}}
+	}
 	client, err := sunlight.NewClient(&sunlight.ClientConfig{
-		MonitoringPrefix: lr,
+		MonitoringPrefix: prefix,
 		PublicKey:        pubK,
+		HTTPClient:       hc,
 		UserAgent:        "woodpecker/0.1.0 (+https://github.com/mhutchinson/woodpecker)",
 	})
 	if err != nil {
@@ -704,14 +912,21 @@ func newStaticCTLogClient(lr string, origin string, vkey string) (logClient, err
 		origin:   origin,
 		verifier: verifier,
 		client:   client,
+		tiles:    tileReader{layout: staticCTLayout, fetch: fetcher},
 	}, nil
 }
 
+// staticCTLayout is the layout of static CT logs.
+var staticCTLayout, _ = layoutFor("static-ct")
+
 type staticCTLogClient struct {
 	url      string
 	origin   string
 	verifier note.Verifier
 	client   *sunlight.Client
+	tiles    tileReader
+	bundles  bundleCache
+	certLeaves
 
 	sfg singleflight.Group
 }
@@ -768,26 +983,98 @@ func (c *staticCTLogClient) GetCheckpoint() (*model.Checkpoint, error) {
 	return val.(*model.Checkpoint), nil
 }
 
-func (c *staticCTLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) ([]byte, error) {
+func (c *staticCTLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error) {
 	if checkpoint == nil {
 		return nil, errors.New("checkpoint is nil")
 	}
 	if index >= checkpoint.Size {
 		return nil, fmt.Errorf("index %d out of bounds for checkpoint size %d", index, checkpoint.Size)
 	}
+	// The data tile is read rather than using the sunlight client, which
+	// only returns entries whose proof verifies.
+	n, width := index/256, min(256, checkpoint.Size-index/256*256)
+	tile, err := c.bundles.get(n, width, func() ([][]byte, error) {
+		p := staticCTLayout.leafPaths(n, width)[0]
+		b, full, err := c.tiles.fetchPartial(context.Background(), p, staticCTLayout.leafPaths(n, 256)[0], width)
+		if err != nil {
+			return nil, err
+		}
+		leaves, hashes, err := staticCTLayout.leaves(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		if full && uint64(len(leaves)) > width {
+			leaves, hashes = leaves[:width], hashes[:width]
+		}
+		if uint64(len(leaves)) != width {
+			return nil, fmt.Errorf("data tile truncated: %s has %d entries, want %d", p, len(leaves), width)
+		}
+		// Each leaf is cached with its leaf hash.
+		return append(leaves, hashes...), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch entry %d: %w", index, err)
+	}
+	leaf, leafHash := tile[index%256], tile[width+index%256]
+
 	var th tlog.Hash
 	copy(th[:], checkpoint.Hash)
 	tree := tlog.Tree{N: int64(checkpoint.Size), Hash: th}
+	rp, err := tlog.ProveRecord(tree.N, int64(index), tlog.TileHashReader(tree, c.tiles))
+	if err != nil {
+		return &model.Leaf{Contents: leaf, Index: index, LeafHash: leafHash}, fmt.Errorf("failed to build inclusion proof: %w", err)
+	}
+	return verifyLeaf(checkpoint, leaf, &model.InclusionProof{
+		Index:    index,
+		TreeSize: checkpoint.Size,
+		LeafHash: leafHash,
+		Hashes:   recordProofHashes(rp),
+	})
+}
+
+// certLeaves renders and exports leaves holding certificates, for CT logs.
+type certLeaves struct{}
 
-	entry, _, err := c.client.Entry(context.Background(), tree, int64(index))
+func (certLeaves) FormatLeaf(leaf []byte) string {
+	cert, err := leafCertificate(leaf)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch entry %d: %w", index, err)
+		return fmt.Sprintf("Failed to parse cert: %v", err)
 	}
+	if cert == nil {
+		return string(leaf)
+	}
+	return formatCert(cert)
+}
+
+// LeafFields returns the names of the fields of a certificate that are
+// exported as CSV.
+func (certLeaves) LeafFields() []string {
+	return []string{"subject", "issuer", "serial_number", "not_before", "not_after", "dns_names"}
+}
 
-	return json.Marshal(entry)
+// LeafValues returns the LeafFields of the certificate in a leaf.
+func (certLeaves) LeafValues(leaf []byte) ([]string, error) {
+	cert, err := leafCertificate(leaf)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, errors.New("leaf is not a certificate")
+	}
+	return []string{
+		cert.Subject.String(),
+		cert.Issuer.String(),
+		cert.SerialNumber.String(),
+		cert.NotBefore.Format(time.RFC3339),
+		cert.NotAfter.Format(time.RFC3339),
+		strings.Join(cert.DNSNames, " "),
+	}, nil
 }
 
-func (c *staticCTLogClient) FormatLeaf(leaf []byte) string {
+// leafCertificate returns the certificate or precertificate in a leaf,
+// which is either a JSON encoded entry or a DER certificate. It returns nil
+// if the leaf holds neither.
+func leafCertificate(leaf []byte) (*x509.Certificate, error) {
 	var entry struct {
 		Certificate    []byte
 		IsPrecert      bool
@@ -796,9 +1083,9 @@ func (c *staticCTLogClient) FormatLeaf(leaf []byte) string {
 	if err := json.Unmarshal(leaf, &entry); err != nil {
 		cert, err := x509.ParseCertificate(leaf)
 		if err != nil {
-			return string(leaf)
+			return nil, nil
 		}
-		return formatCert(cert)
+		return cert, nil
 	}
 
 	certBytes := entry.Certificate
@@ -806,13 +1093,9 @@ func (c *staticCTLogClient) FormatLeaf(leaf []byte) string {
 		certBytes = entry.PreCertificate
 	}
 	if len(certBytes) == 0 {
-		return string(leaf)
-	}
-	cert, err := x509.ParseCertificate(certBytes)
-	if err != nil {
-		return fmt.Sprintf("Failed to parse cert: %v", err)
+		return nil, nil
 	}
-	return formatCert(cert)
+	return x509.ParseCertificate(certBytes)
 }
 
 func formatCert(cert *x509.Certificate) string {
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	serverless_client "github.com/transparency-dev/serverless-log/client"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/klog/v2"
+)
+
+// mirrorConcurrency is how many of a tile's leaf files are fetched at once.
+const mirrorConcurrency = 8
+
+// runMirror runs the mirror command, which mirrors the log chosen with
+// --origin, or else the custom log or first built-in log, into the
+// directory given in args.
+func runMirror(args []string) {
+	if len(args) != 1 {
+		klog.Exitf("Usage: woodpecker [flags] mirror DIR")
+	}
+	client := commandClient()
+	dir, err := filepath.Abs(args[0])
+	if err != nil {
+		klog.Exitf("Invalid directory %q: %v", args[0], err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cp, stats, err := mirrorLog(ctx, client, dir, os.Stderr)
+	if err != nil {
+		if ctx.Err() != nil {
+			klog.Exitf("Mirror of %q interrupted; run the command again to resume it", client.GetOrigin())
+		}
+		klog.Exitf("Failed to mirror %q: %v", client.GetOrigin(), err)
+	}
+	fmt.Printf("Mirrored %q at size %d to %s: fetched %d files, %d already present", client.GetOrigin(), cp.Size, dir, stats.fetched.Load(), stats.reused.Load())
+	if n := stats.replaced.Load(); n > 0 {
+		fmt.Printf(", %d replaced as they failed verification", n)
+	}
+	fmt.Printf("\nOpen it with --custom_log_type=%s --custom_log_url=%s --custom_log_origin=%q and the log's --custom_log_vkey\n", client.GetLogType(), (&url.URL{Scheme: "file", Path: dir + "/"}).String(), client.GetOrigin())
+}
+
+// mirrorStats counts the files handled by a mirror.
+type mirrorStats struct {
+	// fetched files were fetched from the log, and reused files were
+	// already in the mirror.
+	fetched, reused atomic.Int64
+	// replaced files were in the mirror, but didn't verify and so were
+	// fetched again.
+	replaced atomic.Int64
+}
+
+// mirror copies a log into a local directory with the same layout.
+type mirror struct {
+	fetch    serverless_client.Fetcher
+	dir      string
+	layout   *tileLayout
+	size     uint64
+	progress io.Writer
+	leaves   uint64
+	stats    mirrorStats
+}
+
+// mirrorLog copies the checkpoint, hash tiles and leaves of the log into
+// dir, verifying everything against the log's checkpoint as it goes. Files
+// already in dir are reused if they verify, so an interrupted mirror can be
+// resumed, or a mirror brought up to date, by running it again. The mirror
+// can then be opened with a file:// URL. Progress is written to progress.
+func mirrorLog(ctx context.Context, client logClient, dir string, progress io.Writer) (*log.Checkpoint, *mirrorStats, error) {
+	layout, err := layoutFor(client.GetLogType())
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := url.Parse(client.GetURL())
+	if err != nil {
+		return nil, nil, err
+	}
+	fetch, err := newLogFetcher(client.GetOrigin(), root)
+	if err != nil {
+		return nil, nil, err
+	}
+	cpRaw, err := fetch(ctx, layout.checkpointPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching checkpoint: %w", err)
+	}
+	cp, _, _, err := log.ParseCheckpoint(cpRaw, client.GetOrigin(), client.GetVerifier())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+	m := &mirror{fetch: fetch, dir: dir, layout: layout, size: cp.Size, progress: progress}
+	m.stats.fetched.Add(1)
+	if err := m.run(ctx, cp.Hash); err != nil {
+		return nil, &m.stats, err
+	}
+	// The checkpoint is written last, so that the mirror's checkpoint only
+	// ever commits to files which are in the mirror.
+	if err := writeFileAtomic(m.localPath(layout.checkpointPath), cpRaw); err != nil {
+		return nil, &m.stats, err
+	}
+	return cp, &m.stats, nil
+}
+
+// edgeTile is a partial tile on the right edge of the tree.
+type edgeTile struct {
+	level, index uint64
+	path         string
+	data         []byte
+	hashes       [][]byte
+}
+
+// run mirrors the tree with the given root hash. The partial tiles on the
+// right edge of the tree, at most one per level, together hold the tree's
+// compact range, and so are verified against the root hash. Every full
+// tile is then verified against its node in its parent, from the top down.
+func (m *mirror) run(ctx context.Context, rootHash []byte) error {
+	if m.size == 0 {
+		if !bytes.Equal(rootHash, rfc6962.DefaultHasher.EmptyRoot()) {
+			return errors.New("empty log has the wrong root hash")
+		}
+		return nil
+	}
+	var edges []edgeTile
+	for level := uint64(0); m.size>>(8*level) > 0; level++ {
+		n := m.size >> (8 * level)
+		if n%256 == 0 {
+			continue
+		}
+		// Edge tiles are small, and are fetched every time as they can't
+		// be verified alone. They are saved once the root hash matches.
+		e := edgeTile{level: level, index: n / 256, path: m.layout.tilePath(level, n/256, n%256)}
+		var err error
+		if e.data, err = m.fetch(ctx, e.path); err != nil {
+			return fmt.Errorf("fetching %s: %w", e.path, err)
+		}
+		if e.hashes, err = m.layout.tileHashes(e.data, n%256); err != nil {
+			return fmt.Errorf("%s: %w", e.path, err)
+		}
+		m.stats.fetched.Add(1)
+		edges = append(edges, e)
+	}
+
+	// The compact range is the subtrees of the top edge tile, followed by
+	// those of each lower edge tile.
+	var nodes [][]byte
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	for i := len(edges) - 1; i >= 0; i-- {
+		cr := rf.NewEmptyRange(0)
+		for _, h := range edges[i].hashes {
+			if err := cr.Append(h, nil); err != nil {
+				return err
+			}
+		}
+		nodes = append(nodes, cr.Hashes()...)
+	}
+	root := nodes[len(nodes)-1]
+	for i := len(nodes) - 2; i >= 0; i-- {
+		root = rfc6962.DefaultHasher.HashChildren(nodes[i], root)
+	}
+	if !bytes.Equal(root, rootHash) {
+		return fmt.Errorf("tiles on the right edge of the tree have root hash %x, but the checkpoint has %x", root, rootHash)
+	}
+	for _, e := range edges {
+		if err := writeFileAtomic(m.localPath(e.path), e.data); err != nil {
+			return err
+		}
+	}
+
+	// Mirror from the top edge tile down, so that leaves are mirrored in
+	// order.
+	for i := len(edges) - 1; i >= 0; i-- {
+		if err := m.subtrees(ctx, edges[i].level, edges[i].index, edges[i].hashes); err != nil {
+			return err
+		}
+	}
+	if m.progress != nil {
+		fmt.Fprintln(m.progress)
+	}
+	return nil
+}
+
+// subtrees mirrors the subtrees below the verified bottom row of the tile
+// at level and index.
+func (m *mirror) subtrees(ctx context.Context, level, index uint64, hashes [][]byte) error {
+	if level == 0 {
+		return m.mirrorLeaves(ctx, index, hashes)
+	}
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	for i, want := range hashes {
+		child := index*256 + uint64(i)
+		var childHashes [][]byte
+		_, err := m.file(ctx, m.layout.tilePath(level-1, child, 256), func(b []byte) error {
+			hs, err := m.layout.tileHashes(b, 256)
+			if err != nil {
+				return err
+			}
+			cr := rf.NewEmptyRange(0)
+			for _, h := range hs {
+				if err := cr.Append(h, nil); err != nil {
+					return err
+				}
+			}
+			root, err := cr.GetRootHash(nil)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(root, want) {
+				return fmt.Errorf("tile has root hash %x, but its parent has %x", root, want)
+			}
+			childHashes = hs
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := m.subtrees(ctx, level-1, child, childHashes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mirrorLeaves mirrors the leaves under the level 0 tile at index, whose
+// bottom row is hashes.
+func (m *mirror) mirrorLeaves(ctx context.Context, index uint64, hashes [][]byte) error {
+	paths := m.layout.leafPaths(index, uint64(len(hashes)))
+	per := len(hashes) / len(paths)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(mirrorConcurrency)
+	for i, p := range paths {
+		want := hashes[i*per : (i+1)*per]
+		g.Go(func() error {
+			_, err := m.file(ctx, p, func(b []byte) error {
+				_, got, err := m.layout.leaves(b)
+				if err != nil {
+					return err
+				}
+				if len(got) != len(want) {
+					return fmt.Errorf("file has %d leaves, want %d", len(got), len(want))
+				}
+				for j := range got {
+					if !bytes.Equal(got[j], want[j]) {
+						return fmt.Errorf("leaf %d has hash %x, but the tile has %x", index*256+uint64(i*per+j), got[j], want[j])
+					}
+				}
+				return nil
+			})
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	m.leaves += uint64(len(hashes))
+	if m.progress != nil {
+		fmt.Fprintf(m.progress, "\rMirrored %d/%d leaves", m.leaves, m.size)
+	}
+	return nil
+}
+
+// file returns the file at p, from the mirror if it's there and passes
+// check, and otherwise from the log, saving it once it passes check.
+func (m *mirror) file(ctx context.Context, p string, check func([]byte) error) ([]byte, error) {
+	local := m.localPath(p)
+	b, err := os.ReadFile(local)
+	if err == nil {
+		if check(b) == nil {
+			m.stats.reused.Add(1)
+			return b, nil
+		}
+		m.stats.replaced.Add(1)
+	}
+	if b, err = m.fetch(ctx, p); err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", p, err)
+	}
+	if err := check(b); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := writeFileAtomic(local, b); err != nil {
+		return nil, err
+	}
+	m.stats.fetched.Add(1)
+	return b, nil
+}
+
+func (m *mirror) localPath(p string) string {
+	return filepath.Join(m.dir, filepath.FromSlash(p))
+}
+
+// writeFileAtomic writes b to p, creating its directory if need be. The file
+// is written under a temporary name and then renamed, so that p is never
+// left half written.
+func writeFileAtomic(p string, b []byte) error {
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tmp-"+filepath.Base(p)+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// newTestLog returns the files of a log of the given type, "tiles" or
+// "sumdb", holding entries, and the log's verifier key. Entries of sumdb
+// logs must end with a newline.
+func newTestLog(t *testing.T, logType, origin string, entries []string) (map[string][]byte, string) {
+	t.Helper()
+	skey, vkey, err := note.GenerateKey(rand.Reader, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := note.NewSigner(skey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout, err := layoutFor(logType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	tree := rf.NewEmptyRange(0)
+
+	// Each level holds the nodes at level 8L of the tree, which are the
+	// roots of the full tiles at the level below.
+	var level [][]byte
+	for i := 0; i < len(entries); i += 256 {
+		group := entries[i:min(i+256, len(entries))]
+		var data []byte
+		for j, e := range group {
+			switch logType {
+			case "serverless":
+				files[layout.leafPaths(uint64(i/256), uint64(len(group)))[j]] = []byte(e)
+			case "tiles":
+				data = binary.BigEndian.AppendUint16(data, uint16(len(e)))
+				data = append(data, e...)
+			case "sumdb":
+				if len(data) > 0 {
+					data = append(data, '\n')
+				}
+				data = append(data, e...)
+			}
+			h := rfc6962.DefaultHasher.HashLeaf([]byte(e))
+			level = append(level, h)
+			if err := tree.Append(h, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if logType != "serverless" {
+			files[layout.leafPaths(uint64(i/256), uint64(len(group)))[0]] = data
+		}
+	}
+	for l := uint64(0); len(level) > 0; l++ {
+		var next [][]byte
+		for i := 0; i < len(level); i += 256 {
+			group := level[i:min(i+256, len(level))]
+			files[layout.tilePath(l, uint64(i/256), uint64(len(group)))] = bytes.Join(group, nil)
+			if len(group) == 256 {
+				cr := rf.NewEmptyRange(0)
+				for _, h := range group {
+					_ = cr.Append(h, nil)
+				}
+				root, _ := cr.GetRootHash(nil)
+				next = append(next, root)
+			}
+		}
+		level = next
+	}
+
+	root, err := tree.GetRootHash(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := log.Checkpoint{Origin: origin, Size: uint64(len(entries)), Hash: root}
+	signed, err := note.Sign(&note.Note{Text: string(cp.Marshal())}, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files[layout.checkpointPath] = signed
+	return files, vkey
+}
+
+// testLogServer serves files over HTTP, counting the requests for each.
+type testLogServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	files    map[string][]byte
+	requests map[string]int
+}
+
+func newTestLogServer(t *testing.T, files map[string][]byte) *testLogServer {
+	s := &testLogServer{files: files, requests: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, "/")
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests[p]++
+		b, ok := s.files[p]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testEntries(n int, suffix string) []string {
+	entries := make([]string, n)
+	for i := range entries {
+		entries[i] = fmt.Sprintf("entry %d%s", i, suffix)
+	}
+	return entries
+}
+
+func TestMirror(t *testing.T) {
+	const origin = "example.com/mirrored"
+	for _, tc := range []struct {
+		logType string
+		suffix  string
+		open    func(lr, origin, vkey string) (logClient, error)
+	}{
+		{logType: "tiles", open: newTLogTilesLogClient},
+		{logType: "sumdb", suffix: "\n", open: newSumDBLogClient},
+	} {
+		t.Run(tc.logType, func(t *testing.T) {
+			// Three levels of tiles, with full and partial tiles at the
+			// bottom.
+			entries := testEntries(256*256+300, tc.suffix)
+			files, vkey := newTestLog(t, tc.logType, origin, entries)
+			srv := newTestLogServer(t, files)
+			client, err := tc.open(srv.URL, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			var progress bytes.Buffer
+			cp, stats, err := mirrorLog(t.Context(), client, dir, &progress)
+			if err != nil {
+				t.Fatalf("mirrorLog: %v", err)
+			}
+			if cp.Size != uint64(len(entries)) || stats.fetched.Load() != int64(len(files)) {
+				t.Errorf("mirrored size %d with %d files, want %d with %d", cp.Size, stats.fetched.Load(), len(entries), len(files))
+			}
+			if !strings.Contains(progress.String(), fmt.Sprintf("Mirrored %d/%d leaves", len(entries), len(entries))) {
+				t.Errorf("progress %q doesn't report every leaf", progress.String()[max(0, progress.Len()-100):])
+			}
+			for p, want := range files {
+				got, err := os.ReadFile(filepath.Join(dir, p))
+				if err != nil || !bytes.Equal(got, want) {
+					t.Errorf("mirrored %s differs: %v", p, err)
+				}
+			}
+
+			// The mirror can be browsed like the log.
+			mirrored, err := tc.open("file://"+dir, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mcp, err := mirrored.GetCheckpoint()
+			if err != nil {
+				t.Fatalf("GetCheckpoint from mirror: %v", err)
+			}
+			for _, i := range []uint64{0, 256*256 + 1, uint64(len(entries) - 1)} {
+				l, err := mirrored.GetLeaf(mcp, i)
+				if err != nil {
+					t.Fatalf("GetLeaf(%d) from mirror: %v", i, err)
+				}
+				if string(l.Contents) != entries[i] || !l.Verified {
+					t.Errorf("GetLeaf(%d) = %q (verified %v), want verified %q", i, l.Contents, l.Verified, entries[i])
+				}
+			}
+
+			// Mirroring again only fetches the checkpoint and the
+			// partial tiles, which are always fetched.
+			srv.requests = make(map[string]int)
+			if _, stats, err = mirrorLog(t.Context(), client, dir, nil); err != nil {
+				t.Fatalf("resuming mirrorLog: %v", err)
+			}
+			if got := len(srv.requests); got != 4 || stats.fetched.Load() != 4 {
+				t.Errorf("resumed mirror made requests for %v, want the checkpoint and 3 partial tiles", srv.requests)
+			}
+		})
+	}
+}
+
+func TestMirrorVerifies(t *testing.T) {
+	const origin = "example.com/tampered"
+	entries := testEntries(600, "")
+	layout, _ := layoutFor("tiles")
+	for _, tc := range []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "full hash tile", path: layout.tilePath(0, 1, 256), want: "its parent has"},
+		{name: "partial hash tile", path: layout.tilePath(0, 2, 88), want: "checkpoint has"},
+		{name: "entry bundle", path: layout.leafPaths(0, 256)[0], want: "leaf 3 has hash"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			files, vkey := newTestLog(t, "tiles", origin, entries)
+			srv := newTestLogServer(t, files)
+			client, err := newTLogTilesLogClient(srv.URL, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := t.TempDir()
+			if _, _, err := mirrorLog(t.Context(), client, dir, nil); err != nil {
+				t.Fatalf("mirrorLog: %v", err)
+			}
+
+			// Tampering with the log is detected.
+			orig := files[tc.path]
+			b := bytes.Clone(orig)
+			if tc.name == "entry bundle" {
+				b = bytes.Replace(b, []byte("entry 3"), []byte("entry X"), 1)
+			} else {
+				b[40] ^= 1
+			}
+			srv.files[tc.path] = b
+			if _, _, err := mirrorLog(t.Context(), client, t.TempDir(), nil); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("mirrorLog of tampered log = %v, want error containing %q", err, tc.want)
+			}
+
+			// A tampered file in the mirror is replaced.
+			srv.files[tc.path] = orig
+			if err := os.WriteFile(filepath.Join(dir, tc.path), b, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, stats, err := mirrorLog(t.Context(), client, dir, nil)
+			if err != nil {
+				t.Fatalf("mirrorLog: %v", err)
+			}
+			if tc.name != "partial hash tile" && stats.replaced.Load() != 1 {
+				t.Errorf("replaced %d files, want 1", stats.replaced.Load())
+			}
+			if got, _ := os.ReadFile(filepath.Join(dir, tc.path)); !bytes.Equal(got, orig) {
+				t.Errorf("tampered %s wasn't replaced", tc.path)
+			}
+		})
+	}
+}
+
+func TestMirrorWritesCheckpointLast(t *testing.T) {
+	const origin = "example.com/unavailable"
+	files, vkey := newTestLog(t, "tiles", origin, testEntries(300, ""))
+	layout, _ := layoutFor("tiles")
+	delete(files, layout.leafPaths(1, 44)[0])
+	srv := newTestLogServer(t, files)
+	client, err := newTLogTilesLogClient(srv.URL, origin, vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if _, _, err := mirrorLog(t.Context(), client, dir, nil); err == nil {
+		t.Fatal("mirrorLog with a missing entry bundle succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "checkpoint")); !os.IsNotExist(err) {
+		t.Errorf("checkpoint written for an incomplete mirror: %v", err)
+	}
+	// Everything fetched before the failure is kept for next time.
+	if _, err := os.Stat(filepath.Join(dir, layout.leafPaths(0, 256)[0])); err != nil {
+		t.Errorf("entry bundle fetched before the failure wasn't kept: %v", err)
+	}
+}
@@ -14,4 +14,19 @@ type Checkpoint struct {
 type Leaf struct {
 	Contents []byte
 	Index    uint64
+	LeafHash []byte
+	// Proof is the inclusion proof for the leaf in the tree of size
+	// Proof.TreeSize, or nil if no proof was obtained.
+	Proof *InclusionProof
+	// Verified is true if Proof was verified against the checkpoint root.
+	Verified bool
+}
+
+// InclusionProof is an inclusion proof for a leaf in a tree of a given size.
+// Hashes are ordered from the leaf towards the root, as in RFC 6962.
+type InclusionProof struct {
+	Index    uint64
+	TreeSize uint64
+	LeafHash []byte
+	Hashes   [][]byte
 }
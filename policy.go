@@ -0,0 +1,590 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	tnote "github.com/transparency-dev/formats/note"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// witnessPolicy is a witness policy in the Sigsum policy format
+// (https://git.glasklarteknik.se/sigsum/core/sigsum-go/-/blob/main/doc/policy.md).
+//
+// The format is line based:
+//
+//	log <key> [url]
+//	witness <name> <key> [url]
+//	group <name> <threshold> <member>...
+//	quorum <name>|none
+//
+// where threshold is "all", "any" or a number, and group members are the
+// names of witnesses or groups defined on earlier lines. Witness keys may be
+// Sigsum style hex Ed25519 public keys, or note verifier keys. The witness
+// name in a Sigsum policy is just a local label, so cosignatures are matched
+// to witnesses by their public key, whatever name they are signed under.
+type witnessPolicy struct {
+	logs      []policyLog
+	witnesses []*policyWitness
+	groups    []*policyGroup
+	// quorum is the name of the witness or group that must be satisfied,
+	// or empty if no witnessing is required.
+	quorum string
+
+	byName map[string]policyEntity
+}
+
+type policyLog struct {
+	key string
+	url string
+}
+
+type policyWitness struct {
+	name     string
+	verifier note.Verifier
+	// key is the witness's Ed25519 public key, by which Sigsum logs
+	// identify its cosignatures.
+	key ed25519.PublicKey
+	url string
+}
+
+type policyGroup struct {
+	name      string
+	threshold int
+	members   []string
+}
+
+// policyEntity is a witness or a group that can be named by a group or quorum.
+type policyEntity interface {
+	entityName() string
+}
+
+func (w *policyWitness) entityName() string { return w.name }
+func (g *policyGroup) entityName() string   { return g.name }
+
+// loadWitnessPolicy reads and parses the policy file at path.
+func loadWitnessPolicy(path string) (*witnessPolicy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parseWitnessPolicy(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// loadPinnedWitnesses reads the witnesses to trust from the file at path.
+// The file is in the same format as a witness policy, but the quorum line
+// is optional, so a policy file can also be used to pin its witnesses.
+func loadPinnedWitnesses(path string) ([]note.Verifier, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parsePolicy(b, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p.Verifiers(), nil
+}
+
+func parseWitnessPolicy(b []byte) (*witnessPolicy, error) {
+	return parsePolicy(b, true)
+}
+
+func parsePolicy(b []byte, requireQuorum bool) (*witnessPolicy, error) {
+	p := &witnessPolicy{byName: make(map[string]policyEntity)}
+	sawQuorum := false
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for lineNum := 1; sc.Scan(); lineNum++ {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := p.parseLine(fields, sawQuorum); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if fields[0] == "quorum" {
+			sawQuorum = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if requireQuorum && !sawQuorum {
+		return nil, fmt.Errorf("missing quorum line")
+	}
+	return p, nil
+}
+
+func (p *witnessPolicy) parseLine(fields []string, sawQuorum bool) error {
+	switch keyword, args := fields[0], fields[1:]; keyword {
+	case "log":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("log: expected <key> [url]")
+		}
+		l := policyLog{key: args[0]}
+		if len(args) == 2 {
+			l.url = args[1]
+		}
+		p.logs = append(p.logs, l)
+	case "witness":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("witness: expected <name> <key> [url]")
+		}
+		if err := p.checkNewName(args[0]); err != nil {
+			return err
+		}
+		v, key, err := policyWitnessVerifier(args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("witness %s: %w", args[0], err)
+		}
+		w := &policyWitness{name: args[0], verifier: v, key: key}
+		if len(args) == 3 {
+			w.url = args[2]
+		}
+		p.witnesses = append(p.witnesses, w)
+		p.byName[w.name] = w
+	case "group":
+		if len(args) < 3 {
+			return fmt.Errorf("group: expected <name> <threshold> <member>...")
+		}
+		name, members := args[0], args[2:]
+		if err := p.checkNewName(name); err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, m := range members {
+			if _, ok := p.byName[m]; !ok {
+				return fmt.Errorf("group %s: undefined member %q", name, m)
+			}
+			if seen[m] {
+				return fmt.Errorf("group %s: duplicate member %q", name, m)
+			}
+			seen[m] = true
+		}
+		var threshold int
+		switch args[1] {
+		case "all":
+			threshold = len(members)
+		case "any":
+			threshold = 1
+		default:
+			t, err := strconv.Atoi(args[1])
+			if err != nil || t < 1 || t > len(members) {
+				return fmt.Errorf("group %s: invalid threshold %q", name, args[1])
+			}
+			threshold = t
+		}
+		g := &policyGroup{name: name, threshold: threshold, members: members}
+		p.groups = append(p.groups, g)
+		p.byName[name] = g
+	case "quorum":
+		if sawQuorum {
+			return fmt.Errorf("quorum: defined more than once")
+		}
+		if len(args) != 1 {
+			return fmt.Errorf("quorum: expected <name>")
+		}
+		if args[0] != "none" {
+			if _, ok := p.byName[args[0]]; !ok {
+				return fmt.Errorf("quorum: undefined name %q", args[0])
+			}
+			p.quorum = args[0]
+		}
+	default:
+		return fmt.Errorf("unknown keyword %q", keyword)
+	}
+	return nil
+}
+
+func (p *witnessPolicy) checkNewName(name string) error {
+	if name == "none" {
+		return fmt.Errorf("%q is a reserved name", name)
+	}
+	if _, ok := p.byName[name]; ok {
+		return fmt.Errorf("duplicate name %q", name)
+	}
+	return nil
+}
+
+// policyWitnessVerifier returns a cosignature/v1 verifier for a witness key,
+// which is either a note verifier key or a hex encoded Ed25519 public key,
+// along with the public key. The verifier for a hex key is named after the
+// policy's label for the witness, so it won't verify the witness's own
+// signature lines; see adoptCosignatures.
+func policyWitnessVerifier(name, key string) (note.Verifier, ed25519.PublicKey, error) {
+	if strings.Contains(key, "+") {
+		v, err := tnote.NewVerifierForCosignatureV1(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The key is the last field of the verifier key, after its
+		// algorithm byte. Witnesses may also have other kinds of key.
+		var pub ed25519.PublicKey
+		if k, _ := base64.StdEncoding.DecodeString(strings.SplitN(key, "+", 3)[2]); len(k) == 1+ed25519.PublicKeySize {
+			pub = k[1:]
+		}
+		return v, pub, nil
+	}
+	pub, err := hex.DecodeString(key)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, nil, fmt.Errorf("invalid key %q: expected a note verifier key or hex Ed25519 public key", key)
+	}
+	v, err := tnote.NewVerifierForCosignatureV1(cosignatureV1VKey(name, pub))
+	return v, pub, err
+}
+
+// cosignatureV1VKey returns the note verifier key for the Ed25519
+// cosignature/v1 public key pub, under the given name.
+func cosignatureV1VKey(name string, pub []byte) string {
+	const algEd25519CosignatureV1 = 0x04
+	key := append([]byte{algEd25519CosignatureV1}, pub...)
+	h := sha256.New()
+	h.Write([]byte(name))
+	h.Write([]byte("\n"))
+	h.Write(key)
+	keyHash := binary.BigEndian.Uint32(h.Sum(nil))
+	return fmt.Sprintf("%s+%08x+%s", name, keyHash, base64.StdEncoding.EncodeToString(key))
+}
+
+// Verifiers returns the cosignature verifiers of the witnesses in the policy.
+func (p *witnessPolicy) Verifiers() []note.Verifier {
+	vs := make([]note.Verifier, 0, len(p.witnesses))
+	for _, w := range p.witnesses {
+		vs = append(vs, w.verifier)
+	}
+	return vs
+}
+
+// maxMinWitnessesSearch bounds the number of sets of witnesses that
+// MinWitnesses tries, which only matters for policies with many witnesses.
+const maxMinWitnessesSearch = 1 << 16
+
+// MinWitnesses returns the smallest number of cosignatures that could
+// satisfy the quorum. A witness may be a member of several groups, so this
+// tries sets of the quorum's witnesses in increasing size until one
+// satisfies it. If the search is cut short, no smaller set satisfies the
+// quorum, so the result is still a lower bound.
+func (p *witnessPolicy) MinWitnesses() uint {
+	if p.quorum == "" {
+		return 0
+	}
+	names := p.witnessNames(p.quorum, nil)
+	tries := 0
+	for k := 1; k <= len(names); k++ {
+		found := false
+		combinations(len(names), k, func(set []int) bool {
+			signed := make(map[string]bool, k)
+			for _, i := range set {
+				signed[names[i]] = true
+			}
+			found = p.evaluate(p.quorum, signed).satisfied
+			tries++
+			return !found && tries < maxMinWitnessesSearch
+		})
+		if found || tries >= maxMinWitnessesSearch {
+			return uint(k)
+		}
+	}
+	return uint(len(names))
+}
+
+// witnessNames appends the names of the distinct witnesses under the
+// witness or group name to names.
+func (p *witnessPolicy) witnessNames(name string, names []string) []string {
+	g, ok := p.byName[name].(*policyGroup)
+	if !ok {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+		return names
+	}
+	for _, m := range g.members {
+		names = p.witnessNames(m, names)
+	}
+	return names
+}
+
+// combinations calls f with each set of k of the indexes 0 to n-1, in
+// lexicographic order, until f returns false.
+func combinations(n, k int, f func(set []int) bool) {
+	set := make([]int, k)
+	for i := range set {
+		set[i] = i
+	}
+	for f(set) {
+		// Advance the rightmost index which can move, and reset those
+		// after it.
+		i := k - 1
+		for i >= 0 && set[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		set[i]++
+		for j := i + 1; j < k; j++ {
+			set[j] = set[j-1] + 1
+		}
+	}
+}
+
+// policyResult is the evaluation of a witness or group of a policy against
+// the cosignatures on a checkpoint.
+type policyResult struct {
+	name      string
+	group     bool
+	threshold int
+	satisfied bool
+	members   []*policyResult
+}
+
+// Evaluate checks which witnesses in the policy cosigned n, and whether the
+// quorum is satisfied. Only verified signatures are considered. It returns
+// nil if the policy has no quorum.
+func (p *witnessPolicy) Evaluate(n *note.Note) *policyResult {
+	if p.quorum == "" {
+		return nil
+	}
+	signed := make(map[string]bool)
+	for _, w := range p.witnesses {
+		for _, s := range n.Sigs {
+			if w.cosigned(n.Text, s) {
+				signed[w.name] = true
+			}
+		}
+	}
+	return p.evaluate(p.quorum, signed)
+}
+
+// cosigned reports whether s is a cosignature by w on a note with the given
+// text. Signatures are matched by name and key hash, or failing that by
+// checking them against the witness's public key.
+func (w *policyWitness) cosigned(text string, s note.Signature) bool {
+	if s.Name == w.verifier.Name() && s.Hash == w.verifier.KeyHash() {
+		return true
+	}
+	return w.key != nil && verifyCosignatureV1(w.key, text, s)
+}
+
+// adoptCosignatures moves the unverified signatures on n which are valid
+// cosignatures by a witness in the policy into its verified signatures.
+// Verifying a note needs the witness's name, which a Sigsum policy doesn't
+// give, so this finds cosignatures by checking each against every key.
+func (p *witnessPolicy) adoptCosignatures(n *note.Note) {
+	unverified := n.UnverifiedSigs[:0]
+	for _, s := range n.UnverifiedSigs {
+		adopted := false
+		for _, w := range p.witnesses {
+			if w.key != nil && verifyCosignatureV1(w.key, n.Text, s) {
+				n.Sigs = append(n.Sigs, s)
+				adopted = true
+				break
+			}
+		}
+		if !adopted {
+			unverified = append(unverified, s)
+		}
+	}
+	n.UnverifiedSigs = unverified
+}
+
+// verifyCosignatureV1 reports whether s is a valid cosignature/v1 signature
+// by key on a note with the given text, ignoring its name and key hash.
+func verifyCosignatureV1(key ed25519.PublicKey, text string, s note.Signature) bool {
+	sig, err := base64.StdEncoding.DecodeString(s.Base64)
+	if err != nil || len(sig) != 4+8+ed25519.SignatureSize {
+		return false
+	}
+	msg := fmt.Sprintf("cosignature/v1\ntime %d\n%s", binary.BigEndian.Uint64(sig[4:12]), text)
+	return ed25519.Verify(key, []byte(msg), sig[12:])
+}
+
+func (p *witnessPolicy) evaluate(name string, signed map[string]bool) *policyResult {
+	r := &policyResult{name: name}
+	g, ok := p.byName[name].(*policyGroup)
+	if !ok {
+		r.satisfied = signed[name]
+		return r
+	}
+	r.group = true
+	r.threshold = g.threshold
+	count := 0
+	for _, m := range g.members {
+		mr := p.evaluate(m, signed)
+		if mr.satisfied {
+			count++
+		}
+		r.members = append(r.members, mr)
+	}
+	r.satisfied = count >= g.threshold
+	return r
+}
+
+// Witnesses returns the names of the witnesses under r which satisfied the
+// policy, and those which were missing.
+func (r *policyResult) Witnesses() (present, missing []string) {
+	if !r.group {
+		if r.satisfied {
+			return []string{r.name}, nil
+		}
+		return nil, []string{r.name}
+	}
+	for _, m := range r.members {
+		p, ms := m.Witnesses()
+		present = append(present, p...)
+		missing = append(missing, ms...)
+	}
+	return present, missing
+}
+
+// dedupeVerifiers removes verifiers with the same name and key hash, which
+// would otherwise make note verification fail as ambiguous.
+func dedupeVerifiers(vs []note.Verifier) []note.Verifier {
+	type nameHash struct {
+		name string
+		hash uint32
+	}
+	seen := make(map[nameHash]bool)
+	out := make([]note.Verifier, 0, len(vs))
+	for _, v := range vs {
+		k := nameHash{v.Name(), v.KeyHash()}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+// policyFlag collects the --witness_policy flag values. Each value is either
+// a policy file applied to every log without its own policy, or
+// ORIGIN=FILE to set the policy for a single log.
+type policyFlag map[string]string
+
+func (f policyFlag) String() string {
+	parts := make([]string, 0, len(f))
+	for o, p := range f {
+		if o == "" {
+			parts = append(parts, p)
+		} else {
+			parts = append(parts, o+"="+p)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f policyFlag) Set(v string) error {
+	// Origins are log names, but paths may be anything, so the origin is
+	// up to the first '='.
+	origin, path := "", v
+	if o, p, ok := strings.Cut(v, "="); ok {
+		origin, path = o, p
+	}
+	if path == "" {
+		return fmt.Errorf("missing policy file in %q", v)
+	}
+	if _, ok := f[origin]; ok {
+		return fmt.Errorf("witness policy for %q set more than once", origin)
+	}
+	f[origin] = path
+	return nil
+}
+
+// loadWitnessPolicies loads the policy files named by f, keyed by origin.
+// The default policy, if any, has the empty origin.
+func loadWitnessPolicies(f policyFlag) (map[string]*witnessPolicy, error) {
+	policies := make(map[string]*witnessPolicy, len(f))
+	for origin, path := range f {
+		p, err := loadWitnessPolicy(path)
+		if err != nil {
+			return nil, err
+		}
+		policies[origin] = p
+	}
+	return policies, nil
+}
+
+// renderPolicyResult draws the evaluation of a witness policy as a tree,
+// followed by any cosigners of n which are not named in the policy.
+func renderPolicyResult(p *witnessPolicy, r *policyResult, n *note.Note) string {
+	good := lipgloss.NewStyle().Foreground(lipgloss.Color("#34D399"))
+	bad := lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+
+	var sb strings.Builder
+	if r == nil {
+		sb.WriteString("The witness policy has no quorum, so no cosignatures are required.\n")
+	} else {
+		var walk func(r *policyResult, depth int)
+		walk = func(r *policyResult, depth int) {
+			mark := bad.Render("✗")
+			if r.satisfied {
+				mark = good.Render("✓")
+			}
+			sb.WriteString(strings.Repeat("   ", depth))
+			if r.group {
+				n := 0
+				for _, m := range r.members {
+					if m.satisfied {
+						n++
+					}
+				}
+				fmt.Fprintf(&sb, "%s group %s %s\n", mark, r.name, dim.Render(fmt.Sprintf("(%d of %d, need %d)", n, len(r.members), r.threshold)))
+				for _, m := range r.members {
+					walk(m, depth+1)
+				}
+				return
+			}
+			fmt.Fprintf(&sb, "%s %s\n", mark, r.name)
+		}
+		walk(r, 0)
+	}
+
+	if n == nil {
+		return sb.String()
+	}
+	var others []string
+	for i, s := range n.Sigs {
+		if i == 0 {
+			// The log's own signature.
+			continue
+		}
+		known := false
+		for _, w := range p.witnesses {
+			if w.cosigned(n.Text, s) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			others = append(others, s.Name)
+		}
+	}
+	if len(others) > 0 {
+		sb.WriteString("\nOther cosigners (not in policy):\n")
+		for _, o := range others {
+			sb.WriteString(dim.Render(" • "+o) + "\n")
+		}
+	}
+	return sb.String()
+}
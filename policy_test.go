@@ -0,0 +1,445 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mhutchinson/woodpecker/model"
+	"github.com/transparency-dev/formats/log"
+	tnote "github.com/transparency-dev/formats/note"
+	"golang.org/x/mod/sumdb/note"
+)
+
+type testWitness struct {
+	signer note.Signer
+	vkey   string
+}
+
+func newTestWitness(t *testing.T, name string) testWitness {
+	t.Helper()
+	skey, vkey, err := note.GenerateKey(rand.Reader, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := tnote.NewSignerForCosignatureV1(skey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cvkey, err := tnote.VKeyToCosignatureV1(vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testWitness{signer: s, vkey: cvkey}
+}
+
+// signedCheckpoint returns a checkpoint for origin signed by the log and
+// cosigned by each of the witnesses.
+func signedCheckpoint(t *testing.T, origin string, logSigner note.Signer, witnesses ...testWitness) []byte {
+	t.Helper()
+	return signCheckpoint(t, log.Checkpoint{Origin: origin, Size: 10, Hash: make([]byte, 32)}, logSigner, witnesses...)
+}
+
+// signCheckpoint signs cp with the log signer and each of the witnesses.
+func signCheckpoint(t *testing.T, cp log.Checkpoint, logSigner note.Signer, witnesses ...testWitness) []byte {
+	t.Helper()
+	signers := []note.Signer{logSigner}
+	for _, w := range witnesses {
+		signers = append(signers, w.signer)
+	}
+	b, err := note.Sign(&note.Note{Text: string(cp.Marshal())}, signers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseWitnessPolicy(t *testing.T) {
+	a, b, c := newTestWitness(t, "a"), newTestWitness(t, "b"), newTestWitness(t, "c")
+	policy := fmt.Sprintf(`
+# Two of three witnesses, one of which must be a.
+log 0000000000000000000000000000000000000000000000000000000000000000
+witness a %s
+witness b %s https://b.example.com
+witness c %s
+group bc any b c
+group all-of all a bc
+quorum all-of
+`, a.vkey, b.vkey, c.vkey)
+	p, err := parseWitnessPolicy([]byte(policy))
+	if err != nil {
+		t.Fatalf("parseWitnessPolicy: %v", err)
+	}
+	if got, want := len(p.witnesses), 3; got != want {
+		t.Errorf("got %d witnesses, want %d", got, want)
+	}
+	if got, want := p.witnesses[1].url, "https://b.example.com"; got != want {
+		t.Errorf("got witness url %q, want %q", got, want)
+	}
+	if got, want := p.quorum, "all-of"; got != want {
+		t.Errorf("got quorum %q, want %q", got, want)
+	}
+	if got, want := p.MinWitnesses(), uint(2); got != want {
+		t.Errorf("MinWitnesses() = %d, want %d", got, want)
+	}
+	if got, want := len(p.Verifiers()), 3; got != want {
+		t.Errorf("got %d verifiers, want %d", got, want)
+	}
+	for _, w := range p.witnesses {
+		if len(w.key) != ed25519.PublicKeySize {
+			t.Errorf("witness %s has a %d byte key, want an Ed25519 key", w.name, len(w.key))
+		}
+	}
+}
+
+func TestParseWitnessPolicyHexKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := parseWitnessPolicy([]byte("witness w1 " + hex.EncodeToString(pub) + "\nquorum w1\n"))
+	if err != nil {
+		t.Fatalf("parseWitnessPolicy: %v", err)
+	}
+	v := p.witnesses[0].verifier
+	if got, want := v.Name(), "w1"; got != want {
+		t.Errorf("got verifier name %q, want %q", got, want)
+	}
+	if !pub.Equal(p.witnesses[0].key) {
+		t.Errorf("got witness key %x, want %x", p.witnesses[0].key, pub)
+	}
+	// The derived key must be the one used by cosignature/v1 signers.
+	if _, err := tnote.NewVerifierForCosignatureV1(cosignatureV1VKey("w1", pub)); err != nil {
+		t.Errorf("cosignatureV1VKey produced an invalid key: %v", err)
+	}
+}
+
+func TestParseWitnessPolicyErrors(t *testing.T) {
+	a := newTestWitness(t, "a")
+	for _, tc := range []struct {
+		name, policy, wantErr string
+	}{
+		{name: "no quorum", policy: "witness a " + a.vkey, wantErr: "missing quorum"},
+		{name: "undefined member", policy: "group g any a\nquorum g", wantErr: "undefined member"},
+		{name: "undefined quorum", policy: "quorum g", wantErr: "undefined name"},
+		{name: "duplicate name", policy: "witness a " + a.vkey + "\nwitness a " + a.vkey + "\nquorum a", wantErr: "duplicate name"},
+		{name: "bad threshold", policy: "witness a " + a.vkey + "\ngroup g 2 a\nquorum g", wantErr: "invalid threshold"},
+		{name: "bad key", policy: "witness a nothex\nquorum a", wantErr: "invalid key"},
+		{name: "two quorums", policy: "quorum none\nquorum none", wantErr: "more than once"},
+		{name: "unknown keyword", policy: "witnesses a\nquorum none", wantErr: "unknown keyword"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseWitnessPolicy([]byte(tc.policy))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluateWitnessPolicy(t *testing.T) {
+	const origin = "example.com/log"
+	logSKey, logVKey, err := note.GenerateKey(rand.Reader, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logSigner, err := note.NewSigner(logSKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logVerifier, err := note.NewVerifier(logVKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := newTestWitness(t, "a"), newTestWitness(t, "b"), newTestWitness(t, "c")
+	other := newTestWitness(t, "other")
+	p, err := parseWitnessPolicy(fmt.Appendf(nil, "witness a %s\nwitness b %s\nwitness c %s\ngroup eu 2 a b c\nquorum eu\n", a.vkey, b.vkey, c.vkey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherVerifier, err := tnote.NewVerifierForCosignatureV1(other.vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifiers := append(p.Verifiers(), otherVerifier)
+
+	for _, tc := range []struct {
+		name        string
+		cosigners   []testWitness
+		wantOK      bool
+		wantPresent []string
+		wantMissing []string
+	}{
+		{name: "quorum", cosigners: []testWitness{a, c}, wantOK: true, wantPresent: []string{"a", "c"}, wantMissing: []string{"b"}},
+		{name: "all", cosigners: []testWitness{a, b, c}, wantOK: true, wantPresent: []string{"a", "b", "c"}},
+		{name: "not enough", cosigners: []testWitness{b, other}, wantPresent: []string{"b"}, wantMissing: []string{"a", "c"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bs := signedCheckpoint(t, origin, logSigner, tc.cosigners...)
+			_, _, n, err := log.ParseCheckpoint(bs, origin, logVerifier, verifiers...)
+			if err != nil {
+				t.Fatalf("ParseCheckpoint: %v", err)
+			}
+			r := p.Evaluate(n)
+			if r.satisfied != tc.wantOK {
+				t.Errorf("got satisfied %v, want %v", r.satisfied, tc.wantOK)
+			}
+			present, missing := r.Witnesses()
+			if !slices.Equal(present, tc.wantPresent) || !slices.Equal(missing, tc.wantMissing) {
+				t.Errorf("got present %v missing %v, want present %v missing %v", present, missing, tc.wantPresent, tc.wantMissing)
+			}
+		})
+	}
+}
+
+func TestMinWitnessesOverlappingGroups(t *testing.T) {
+	a, b, c := newTestWitness(t, "a"), newTestWitness(t, "b"), newTestWitness(t, "c")
+	witnesses := fmt.Sprintf("witness a %s\nwitness b %s\nwitness c %s\n", a.vkey, b.vkey, c.vkey)
+	for _, tc := range []struct {
+		name, groups string
+		want         uint
+	}{
+		{name: "no quorum", groups: "quorum none", want: 0},
+		{name: "threshold", groups: "group g 2 a b c\nquorum g", want: 2},
+		// b is in both groups, so a, b and c are enough.
+		{name: "shared member of all", groups: "group g1 all a b\ngroup g2 all b c\ngroup q all g1 g2\nquorum q", want: 3},
+		{name: "shared member of any", groups: "group g1 any a b\ngroup g2 any b c\ngroup q all g1 g2\nquorum q", want: 1},
+		// a counts both for itself and for g1.
+		{name: "nested", groups: "group g1 any a b\ngroup g2 2 a g1 c\nquorum g2", want: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parseWitnessPolicy([]byte(witnesses + tc.groups))
+			if err != nil {
+				t.Fatalf("parseWitnessPolicy: %v", err)
+			}
+			if got := p.MinWitnesses(); got != tc.want {
+				t.Errorf("MinWitnesses() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateWitnessPolicyHexKey(t *testing.T) {
+	const origin = "example.com/log"
+	logSKey, logVKey, err := note.GenerateKey(rand.Reader, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logSigner, err := note.NewSigner(logSKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logVerifier, err := note.NewVerifier(logVKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The policy names the witness differently from its own key name.
+	w := newTestWitness(t, "witness.example.com")
+	_, pub, err := policyWitnessVerifier("w", w.vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := parseWitnessPolicy(fmt.Appendf(nil, "witness local-label %x\nquorum local-label\n", pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := signedCheckpoint(t, origin, logSigner, w)
+	_, _, n, err := log.ParseCheckpoint(bs, origin, logVerifier, p.Verifiers()...)
+	if err != nil {
+		t.Fatalf("ParseCheckpoint: %v", err)
+	}
+	if len(n.UnverifiedSigs) != 1 {
+		t.Fatalf("got %d unverified signatures, want the witness's", len(n.UnverifiedSigs))
+	}
+	p.adoptCosignatures(n)
+	if len(n.Sigs) != 2 || n.Sigs[1].Name != "witness.example.com" || len(n.UnverifiedSigs) != 0 {
+		t.Errorf("got signatures %+v, unverified %+v; want the witness's cosignature verified", n.Sigs, n.UnverifiedSigs)
+	}
+	if r := p.Evaluate(n); !r.satisfied {
+		t.Errorf("policy not satisfied by a cosignature under the witness's own name")
+	}
+
+	// A signature by another key under the same name isn't adopted.
+	other := newTestWitness(t, "witness.example.com")
+	_, _, n, err = log.ParseCheckpoint(signedCheckpoint(t, origin, logSigner, other), origin, logVerifier, p.Verifiers()...)
+	if err != nil {
+		t.Fatalf("ParseCheckpoint: %v", err)
+	}
+	p.adoptCosignatures(n)
+	if len(n.Sigs) != 1 || p.Evaluate(n).satisfied {
+		t.Errorf("cosignature by another key was adopted: %+v", n.Sigs)
+	}
+}
+
+func TestPolicyFlag(t *testing.T) {
+	f := policyFlag{}
+	for _, v := range []string{"default.policy", "go.sum database tree=policies/a=b.policy"} {
+		if err := f.Set(v); err != nil {
+			t.Fatalf("Set(%q): %v", v, err)
+		}
+	}
+	if got, want := f[""], "default.policy"; got != want {
+		t.Errorf("default policy = %q, want %q", got, want)
+	}
+	if got, want := f["go.sum database tree"], "policies/a=b.policy"; got != want {
+		t.Errorf("policy for sumdb = %q, want %q", got, want)
+	}
+	for _, v := range []string{"other.policy", "origin="} {
+		if err := f.Set(v); err == nil {
+			t.Errorf("Set(%q) succeeded, want error", v)
+		}
+	}
+}
+
+func TestWitnessPolicyPanel(t *testing.T) {
+	const origin = "example.com/log"
+	logSKey, logVKey, err := note.GenerateKey(rand.Reader, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logSigner, err := note.NewSigner(logSKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logVerifier, err := note.NewVerifier(logVKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, other := newTestWitness(t, "a"), newTestWitness(t, "b"), newTestWitness(t, "other")
+	p, err := parseWitnessPolicy(fmt.Appendf(nil, "witness a %s\nwitness b %s\ngroup both all a b\nquorum both\n", a.vkey, b.vkey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherVerifier, err := tnote.NewVerifierForCosignatureV1(other.vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &policyLogClient{origin: origin, verifier: logVerifier}
+	dist := &mockDistributor{checkpoint: signedCheckpoint(t, origin, logSigner, a, other)}
+	// The distributor also knows a, so its verifier must not be ambiguous
+	// with the policy's.
+	aVerifier, err := tnote.NewVerifierForCosignatureV1(a.vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel([]string{origin}, map[string]logClient{origin: client}, mockDistributors(dist), []note.Verifier{aVerifier, otherVerifier}, origin, modelOptions{
+		witnessPolicies: map[string]*witnessPolicy{origin: p},
+	})
+	if got, want := m.witnessN, uint(2); got != want {
+		t.Errorf("got witnessN %d, want %d from the policy", got, want)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	msg := m.fetchCheckpointCmd()().(checkpointMsg)
+	if msg.witnessed != nil {
+		t.Errorf("witnessed checkpoint accepted without satisfying the policy")
+	}
+	if msg.policy == nil || msg.policy.satisfied {
+		t.Fatalf("got policy result %+v, want unsatisfied", msg.policy)
+	}
+	m.Update(msg)
+	view := m.View()
+	for _, want := range []string{"Policy: ✗ not satisfied", "Missing: b", "Signed: a"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view does not contain %q:\n%s", want, view)
+		}
+	}
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	if m.activeView != "witnesses" {
+		t.Fatalf("got activeView %q, want witnesses", m.activeView)
+	}
+	view = m.View()
+	for _, want := range []string{"✗ group both", "✓ a", "✗ b", "Other cosigners", "other"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("witnesses view does not contain %q:\n%s", want, view)
+		}
+	}
+
+	// Once b cosigns, the policy is satisfied.
+	dist.checkpoint = signedCheckpoint(t, origin, logSigner, a, b)
+	m.Update(m.fetchCheckpointCmd()())
+	if m.witnessed == nil {
+		t.Fatalf("witnessed checkpoint not accepted once the policy was satisfied")
+	}
+	if view := m.View(); !strings.Contains(view, "✓ group both") {
+		t.Errorf("witnesses view does not show the satisfied group:\n%s", view)
+	}
+}
+
+func TestWitnessNPerLog(t *testing.T) {
+	a, b, c := newTestWitness(t, "a"), newTestWitness(t, "b"), newTestWitness(t, "c")
+	p, err := parseWitnessPolicy(fmt.Appendf(nil, "witness a %s\nwitness b %s\nwitness c %s\ngroup all all a b c\nquorum all\n", a.vkey, b.vkey, c.vkey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := map[string]logClient{"plain": &mockLogClient{}, "strict": &mockLogClient{}}
+	m := NewModel([]string{"plain", "strict"}, clients, nil, nil, "plain", modelOptions{
+		witnessPolicies: map[string]*witnessPolicy{"strict": p},
+	})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'W'}})
+	if m.witnessN != 1 {
+		t.Fatalf("witnessN = %d after W, want 1", m.witnessN)
+	}
+
+	// The policy's minimum, and changes to it, apply only to its log.
+	m.selectLog("strict")
+	if m.witnessN != 3 {
+		t.Errorf("witnessN = %d for the log with a policy, want 3", m.witnessN)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'w'}})
+	for _, target := range m.dashboardTargets() {
+		want := uint(1)
+		if target.policy != nil {
+			want = 3
+		}
+		if target.witnessN != want {
+			t.Errorf("dashboard witnessN = %d with policy %v, want %d", target.witnessN, target.policy != nil, want)
+		}
+	}
+	m.selectLog("plain")
+	if m.witnessN != 1 {
+		t.Errorf("witnessN = %d back on the log without a policy, want the 1 chosen with W", m.witnessN)
+	}
+}
+
+// policyLogClient is a log client which serves no leaves, for tests that only
+// need checkpoints.
+type policyLogClient struct {
+	mockLogClient
+	origin   string
+	verifier note.Verifier
+}
+
+func (c *policyLogClient) GetOrigin() string          { return c.origin }
+func (c *policyLogClient) GetVerifier() note.Verifier { return c.verifier }
+func (c *policyLogClient) GetCheckpoint() (*model.Checkpoint, error) {
+	return &model.Checkpoint{Checkpoint: &log.Checkpoint{Origin: c.origin}, Note: &note.Note{}}, nil
+}
+
+func TestLoadPinnedWitnesses(t *testing.T) {
+	a, b := newTestWitness(t, "a"), newTestWitness(t, "b")
+	path := filepath.Join(t.TempDir(), "witnesses")
+	// Groups are allowed, but unlike a policy the quorum is optional.
+	if err := os.WriteFile(path, fmt.Appendf(nil, "witness a %s\nwitness b %s\ngroup ab any a b\n", a.vkey, b.vkey), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	vs, err := loadPinnedWitnesses(path)
+	if err != nil {
+		t.Fatalf("loadPinnedWitnesses: %v", err)
+	}
+	if got := len(vs); got != 2 {
+		t.Errorf("got %d witnesses, want 2", got)
+	}
+	if _, err := loadWitnessPolicy(path); err == nil {
+		t.Error("expected a witness policy without a quorum to be rejected")
+	}
+}
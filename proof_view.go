@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mhutchinson/woodpecker/model"
+	"github.com/transparency-dev/merkle/rfc6962"
+)
+
+// auditStep is one hash of an inclusion proof, together with where it sits in
+// the tree and the intermediate hash obtained by combining it.
+type auditStep struct {
+	// Level and Index identify the sibling node in the tree. For ephemeral
+	// nodes, Index is the index the node would have if its subtree was full.
+	Level uint
+	Index uint64
+	// Left is true if the sibling is hashed on the left of the running hash.
+	Left bool
+	// Ephemeral is true if the sibling covers an incomplete subtree on the
+	// right edge of the tree, and so is not stored in any tile.
+	Ephemeral bool
+	// TileLevel and TileIndex are the coordinates of the hash tile (of height
+	// 8) that the sibling is stored in, or derived from.
+	TileLevel uint64
+	TileIndex uint64
+	Sibling   []byte
+	// Hash is the running hash after combining Sibling.
+	Hash []byte
+}
+
+// auditPath walks the inclusion proof from the leaf to the root, returning
+// each step taken. This mirrors the RFC 6962 verification algorithm.
+func auditPath(p *model.InclusionProof) ([]auditStep, error) {
+	if p.Index >= p.TreeSize {
+		return nil, fmt.Errorf("index %d out of range for tree size %d", p.Index, p.TreeSize)
+	}
+	h := rfc6962.DefaultHasher
+	// The inner part of the path is below the point where the path to the
+	// leaf diverges from the right border of the tree.
+	inner := bits.Len64(p.Index ^ (p.TreeSize - 1))
+	border := bits.OnesCount64(p.Index >> uint(inner))
+	if got, want := len(p.Hashes), inner+border; got != want {
+		return nil, fmt.Errorf("wrong proof size %d, want %d", got, want)
+	}
+
+	steps := make([]auditStep, 0, len(p.Hashes))
+	running := p.LeafHash
+	level := uint(0)
+	for _, sibling := range p.Hashes {
+		if int(level) >= inner {
+			// Border nodes are always on the left, and only exist at the
+			// levels where the leaf index has a set bit.
+			for (p.Index>>level)&1 == 0 {
+				level++
+			}
+		}
+		idx := (p.Index >> level) ^ 1
+		s := auditStep{
+			Level:   level,
+			Index:   idx,
+			Left:    (p.Index>>level)&1 == 1,
+			Sibling: sibling,
+		}
+		if !s.Left && (idx+1)<<level > p.TreeSize {
+			s.Ephemeral = true
+		}
+		s.TileLevel, s.TileIndex = tileCoords(level, idx)
+		if s.Left {
+			running = h.HashChildren(sibling, running)
+		} else {
+			running = h.HashChildren(running, sibling)
+		}
+		s.Hash = running
+		steps = append(steps, s)
+		level++
+	}
+	return steps, nil
+}
+
+// tileCoords returns the coordinates of the hash tile which stores, or from
+// which can be computed, the node at the given level and index.
+func tileCoords(level uint, index uint64) (uint64, uint64) {
+	const tileHeight = 8
+	return uint64(level / tileHeight), index >> (tileHeight - level%tileHeight)
+}
+
+// shortHash abbreviates a hash for display in narrow panels.
+func shortHash(h []byte) string {
+	s := fmt.Sprintf("%x", h)
+	if len(s) > 16 {
+		return s[:16] + "…"
+	}
+	return s
+}
+
+// renderAuditPath draws the audit path of p, checking the resulting root
+// against the given checkpoint root hash.
+func renderAuditPath(p *model.InclusionProof, root []byte) string {
+	steps, err := auditPath(p)
+	if err != nil {
+		return fmt.Sprintf("Invalid inclusion proof: %v", err)
+	}
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+	good := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#34D399"))
+	bad := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#F87171"))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Leaf %d in tree of size %d (%d proof hashes)\n", p.Index, p.TreeSize, len(p.Hashes))
+	fmt.Fprintf(&sb, "Leaf hash: %x\n\n", p.LeafHash)
+	sb.WriteString(dim.Render(fmt.Sprintf("%-5s %-6s %-12s %-12s %-18s   %s", "Level", "Side", "Node", "Tile (L/N)", "Proof hash", "Hash")))
+	sb.WriteString("\n")
+	for _, s := range steps {
+		side := "right"
+		if s.Left {
+			side = "left"
+		}
+		node := fmt.Sprintf("%d", s.Index)
+		if s.Ephemeral {
+			node += "*"
+		}
+		fmt.Fprintf(&sb, "%-5d %-6s %-12s %-12s %-18s → %s\n", s.Level, side, node, fmt.Sprintf("%d/%d", s.TileLevel, s.TileIndex), shortHash(s.Sibling), shortHash(s.Hash))
+	}
+	sb.WriteString("\n")
+
+	computed := p.LeafHash
+	if len(steps) > 0 {
+		computed = steps[len(steps)-1].Hash
+	}
+	fmt.Fprintf(&sb, "Computed root:   %x\n", computed)
+	fmt.Fprintf(&sb, "Checkpoint root: %x\n", root)
+	if bytes.Equal(computed, root) {
+		sb.WriteString(good.Render("✓ Root matches checkpoint"))
+	} else {
+		sb.WriteString(bad.Render("✗ Root does not match checkpoint"))
+	}
+	if hasEphemeral(steps) {
+		sb.WriteString("\n")
+		sb.WriteString(dim.Render("* ephemeral node: hash of an incomplete subtree on the right edge of the tree"))
+	}
+	return sb.String()
+}
+
+func hasEphemeral(steps []auditStep) bool {
+	for _, s := range steps {
+		if s.Ephemeral {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mhutchinson/woodpecker/model"
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/merkle/testonly"
+)
+
+func TestAuditPath(t *testing.T) {
+	tree := testonly.New(rfc6962.DefaultHasher)
+	for i := 0; i < 600; i++ {
+		tree.AppendData([]byte(fmt.Sprintf("leaf %d", i)))
+	}
+
+	for _, tc := range []struct {
+		index, size uint64
+	}{
+		{0, 1}, {0, 2}, {1, 2}, {5, 7}, {6, 7}, {255, 256}, {255, 257}, {256, 257}, {300, 513}, {599, 600},
+	} {
+		t.Run(fmt.Sprintf("%d/%d", tc.index, tc.size), func(t *testing.T) {
+			hashes, err := tree.InclusionProof(tc.index, tc.size)
+			if err != nil {
+				t.Fatalf("InclusionProof: %v", err)
+			}
+			p := &model.InclusionProof{
+				Index:    tc.index,
+				TreeSize: tc.size,
+				LeafHash: tree.LeafHash(tc.index),
+				Hashes:   hashes,
+			}
+			steps, err := auditPath(p)
+			if err != nil {
+				t.Fatalf("auditPath: %v", err)
+			}
+			root := p.LeafHash
+			if len(steps) > 0 {
+				root = steps[len(steps)-1].Hash
+			}
+			if want := tree.HashAt(tc.size); !bytes.Equal(root, want) {
+				t.Errorf("computed root %x, want %x", root, want)
+			}
+			for _, s := range steps {
+				if s.Ephemeral {
+					continue
+				}
+				// Non-ephemeral siblings must be the hash of the full
+				// subtree at their coordinates.
+				begin, end := s.Index<<s.Level, (s.Index+1)<<s.Level
+				if got, want := s.Sibling, subtreeHash(tree, begin, end); !bytes.Equal(got, want) {
+					t.Errorf("sibling at level %d index %d: got %x, want %x", s.Level, s.Index, got, want)
+				}
+			}
+		})
+	}
+}
+
+// subtreeHash computes the hash of the perfect subtree covering [begin, end).
+func subtreeHash(tree *testonly.Tree, begin, end uint64) []byte {
+	if end-begin == 1 {
+		return tree.LeafHash(begin)
+	}
+	mid := begin + (end-begin)/2
+	return rfc6962.DefaultHasher.HashChildren(subtreeHash(tree, begin, mid), subtreeHash(tree, mid, end))
+}
+
+func TestAuditPathEphemeralAndTiles(t *testing.T) {
+	tree := testonly.New(rfc6962.DefaultHasher)
+	for i := 0; i < 7; i++ {
+		tree.AppendData([]byte(fmt.Sprintf("leaf %d", i)))
+	}
+	hashes, err := tree.InclusionProof(1, 7)
+	if err != nil {
+		t.Fatalf("InclusionProof: %v", err)
+	}
+	steps, err := auditPath(&model.InclusionProof{Index: 1, TreeSize: 7, LeafHash: tree.LeafHash(1), Hashes: hashes})
+	if err != nil {
+		t.Fatalf("auditPath: %v", err)
+	}
+	// Leaf 1 of 7: left sibling 0, right sibling [2,4), right ephemeral [4,7).
+	want := []struct {
+		level     uint
+		index     uint64
+		left      bool
+		ephemeral bool
+	}{
+		{0, 0, true, false},
+		{1, 1, false, false},
+		{2, 1, false, true},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("got %d steps, want %d", len(steps), len(want))
+	}
+	for i, w := range want {
+		s := steps[i]
+		if s.Level != w.level || s.Index != w.index || s.Left != w.left || s.Ephemeral != w.ephemeral {
+			t.Errorf("step %d: got level=%d index=%d left=%t ephemeral=%t, want %+v", i, s.Level, s.Index, s.Left, s.Ephemeral, w)
+		}
+	}
+
+	for _, tc := range []struct {
+		level     uint
+		index     uint64
+		tileLevel uint64
+		tileIndex uint64
+	}{
+		{0, 255, 0, 0},
+		{0, 256, 0, 1},
+		{1, 128, 0, 1},
+		{7, 3, 0, 1},
+		{8, 256, 1, 1},
+		{9, 5, 1, 0},
+	} {
+		if tl, ti := tileCoords(tc.level, tc.index); tl != tc.tileLevel || ti != tc.tileIndex {
+			t.Errorf("tileCoords(%d, %d) = %d/%d, want %d/%d", tc.level, tc.index, tl, ti, tc.tileLevel, tc.tileIndex)
+		}
+	}
+}
+
+func TestRenderAuditPathMismatch(t *testing.T) {
+	tree := testonly.New(rfc6962.DefaultHasher)
+	tree.AppendData([]byte("a"), []byte("b"), []byte("c"))
+	hashes, err := tree.InclusionProof(2, 3)
+	if err != nil {
+		t.Fatalf("InclusionProof: %v", err)
+	}
+	p := &model.InclusionProof{Index: 2, TreeSize: 3, LeafHash: tree.LeafHash(2), Hashes: hashes}
+	if out := renderAuditPath(p, tree.Hash()); !strings.Contains(out, "Root matches checkpoint") {
+		t.Errorf("expected matching root, got:\n%s", out)
+	}
+	if out := renderAuditPath(p, make([]byte, 32)); !strings.Contains(out, "Root does not match checkpoint") {
+		t.Errorf("expected mismatching root, got:\n%s", out)
+	}
+	p.Hashes = append(p.Hashes, p.Hashes[0])
+	if out := renderAuditPath(p, tree.Hash()); !strings.Contains(out, "Invalid inclusion proof") {
+		t.Errorf("expected invalid proof error, got:\n%s", out)
+	}
+}
+
+func TestProofPanelToggle(t *testing.T) {
+	clients := map[string]logClient{
+		"origin": &mockLogClient{},
+	}
+	m := NewModel([]string{"origin"}, clients, mockDistributors(&mockDistributor{}), nil, "origin", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	tree := testonly.New(rfc6962.DefaultHasher)
+	for i := 0; i < 10; i++ {
+		tree.AppendData([]byte(fmt.Sprintf("leaf %d", i)))
+	}
+	hashes, err := tree.InclusionProof(3, 10)
+	if err != nil {
+		t.Fatalf("InclusionProof: %v", err)
+	}
+	cp := &model.Checkpoint{Checkpoint: &log.Checkpoint{Size: 10, Hash: tree.Hash()}}
+	m.checkpoint = cp
+	m.Update(leafMsg{
+		leaf: model.Leaf{
+			Index:    3,
+			Contents: []byte("leaf 3"),
+			Proof:    &model.InclusionProof{Index: 3, TreeSize: 10, LeafHash: tree.LeafHash(3), Hashes: hashes},
+			Verified: true,
+		},
+		checkpoint: cp,
+	})
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if m.activeView != "proof" {
+		t.Fatalf("expected activeView to be 'proof', got %s", m.activeView)
+	}
+	if out := m.proofView.View(); !strings.Contains(out, "Leaf 3 in tree of size 10") {
+		t.Errorf("expected proof panel for leaf 3, got:\n%s", out)
+	}
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.activeView != "leaf" {
+		t.Errorf("expected activeView to be 'leaf', got %s", m.activeView)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// defaultRefreshInterval is how often a log's checkpoint is fetched
+	// unless set with --refresh_interval.
+	defaultRefreshInterval = 5 * time.Second
+	// maxRefreshBackoff is the longest the next fetch is delayed after
+	// repeated failures.
+	maxRefreshBackoff = 5 * time.Minute
+)
+
+// refreshDelay returns how long to wait before fetching the checkpoint
+// again, doubling interval for each consecutive failure.
+func refreshDelay(interval time.Duration, failures int) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < maxRefreshBackoff; i++ {
+		d *= 2
+	}
+	return min(d, max(interval, maxRefreshBackoff))
+}
+
+// refreshFlag collects the --refresh_interval flag values. Each value is
+// either an interval applied to every log without its own, or
+// ORIGIN=DURATION to set the interval for a single log.
+type refreshFlag map[string]time.Duration
+
+func (f refreshFlag) String() string {
+	parts := make([]string, 0, len(f))
+	for o, d := range f {
+		if o == "" {
+			parts = append(parts, d.String())
+		} else {
+			parts = append(parts, o+"="+d.String())
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f refreshFlag) Set(v string) error {
+	origin, s := "", v
+	if i := strings.LastIndexByte(v, '='); i >= 0 {
+		origin, s = v[:i], v[i+1:]
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid refresh interval in %q: %v", v, err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("refresh interval in %q must be positive", v)
+	}
+	if _, ok := f[origin]; ok {
+		return fmt.Errorf("refresh interval for %q set more than once", origin)
+	}
+	f[origin] = d
+	return nil
+}
+
+// refreshInterval returns how often the current log's checkpoint is
+// fetched when it is being fetched successfully.
+func (m *Model) refreshInterval() time.Duration {
+	if d, ok := m.refreshIntervals[m.currentLog]; ok {
+		return d
+	}
+	if d, ok := m.refreshIntervals[""]; ok {
+		return d
+	}
+	return defaultRefreshInterval
+}
+
+// startPeriodicTicker schedules the next checkpoint fetch, backing off if
+// the last fetches failed. Any previously scheduled fetch is cancelled, so
+// that only one refresh is ever pending.
+func (m *Model) startPeriodicTicker() tea.Cmd {
+	m.tickGen++
+	gen := m.tickGen
+	d := refreshDelay(m.refreshInterval(), m.refreshFailures)
+	m.nextRefresh = time.Now().Add(d)
+	return tea.Tick(d, func(t time.Time) tea.Msg {
+		return tickMsg{gen: gen}
+	})
+}
+
+// refreshStatus describes when the checkpoint will next be fetched.
+func (m *Model) refreshStatus(now time.Time) string {
+	if m.nextRefresh.IsZero() {
+		return ""
+	}
+	d := max(m.nextRefresh.Sub(now), 0).Round(time.Second)
+	if m.refreshFailures > 0 {
+		return fmt.Sprintf("retry in %s", d)
+	}
+	return fmt.Sprintf("refresh in %s", d)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRefreshDelay(t *testing.T) {
+	for _, tc := range []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{interval: 5 * time.Second, failures: 0, want: 5 * time.Second},
+		{interval: 5 * time.Second, failures: 1, want: 10 * time.Second},
+		{interval: 5 * time.Second, failures: 3, want: 40 * time.Second},
+		{interval: 5 * time.Second, failures: 100, want: maxRefreshBackoff},
+		// A long interval isn't shortened by the backoff cap.
+		{interval: time.Hour, failures: 2, want: time.Hour},
+	} {
+		if got := refreshDelay(tc.interval, tc.failures); got != tc.want {
+			t.Errorf("refreshDelay(%v, %d) = %v, want %v", tc.interval, tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestRefreshFlag(t *testing.T) {
+	f := refreshFlag{}
+	for _, v := range []string{"1m", "go.sum database tree=30s"} {
+		if err := f.Set(v); err != nil {
+			t.Fatalf("Set(%q): %v", v, err)
+		}
+	}
+	if got, want := f[""], time.Minute; got != want {
+		t.Errorf("default interval = %v, want %v", got, want)
+	}
+	if got, want := f["go.sum database tree"], 30*time.Second; got != want {
+		t.Errorf("interval for sumdb = %v, want %v", got, want)
+	}
+	for _, v := range []string{"2m", "soon", "origin=-1s"} {
+		if err := f.Set(v); err == nil {
+			t.Errorf("Set(%q) succeeded, want error", v)
+		}
+	}
+}
+
+func TestRefreshBackoff(t *testing.T) {
+	m := NewModel([]string{"a", "b"}, map[string]logClient{"a": &mockLogClient{}, "b": &mockLogClient{}}, nil, nil, "a", modelOptions{
+		refreshIntervals: map[string]time.Duration{"": time.Minute, "b": 10 * time.Second},
+	})
+
+	start := time.Now()
+	m.Update(checkpointMsg{origin: "a", err: errors.New("unavailable")})
+	m.Update(checkpointMsg{origin: "a", err: errors.New("unavailable")})
+	if got, want := m.nextRefresh.Sub(start), 4*time.Minute; got < want {
+		t.Errorf("next refresh in %v after two failures, want at least %v", got, want)
+	}
+	if got := m.refreshStatus(start); !strings.HasPrefix(got, "retry in") {
+		t.Errorf("refreshStatus = %q, want a retry", got)
+	}
+
+	// Ticks from before the last checkpoint was fetched are ignored.
+	if _, cmd := m.Update(tickMsg{gen: m.tickGen - 1}); cmd != nil {
+		if msg := cmd(); msg != nil {
+			t.Errorf("stale tick fetched the checkpoint: %T", msg)
+		}
+	}
+
+	m.selectLog("b")
+	start = time.Now()
+	m.Update(checkpointMsg{origin: "b"})
+	if got, want := m.nextRefresh.Sub(start), 10*time.Second; got < want || got > want+time.Second {
+		t.Errorf("next refresh in %v, want %v for log b", got, want)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"filippo.io/sunlight"
+	"github.com/mhutchinson/woodpecker/model"
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/proof"
+	"github.com/transparency-dev/merkle/rfc6962"
+	serverless_client "github.com/transparency-dev/serverless-log/client"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func newRFC6962LogClient(lr string, origin string, vkey string) (logClient, error) {
+	if !strings.HasSuffix(lr, "/") {
+		lr = lr + "/"
+	}
+	logRoot, err := url.Parse(lr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL %q: %w", lr, err)
+	}
+	// RFC 6962 tree heads don't name the log, so checkpoints are given the
+	// name Sunlight would: the log's URL without its scheme.
+	if len(origin) == 0 {
+		origin = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(lr, "https://"), "http://"), "/")
+	}
+	verifier, err := parseVerifierKey(vkey, origin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse verifier key: %w", err)
+	}
+	fetcher, err := newLogFetcher(origin, logRoot)
+	if err != nil {
+		return nil, err
+	}
+	return &rfc6962LogClient{
+		url:      lr,
+		origin:   origin,
+		verifier: verifier,
+		fetcher:  fetcher,
+	}, nil
+}
+
+// rfc6962LogClient reads CT logs through the JSON API of RFC 6962.
+type rfc6962LogClient struct {
+	url      string
+	origin   string
+	verifier note.Verifier
+	fetcher  serverless_client.Fetcher
+	bundles  bundleCache
+	certLeaves
+
+	// mu guards the largest tree head seen, which every later one must be
+	// consistent with.
+	mu   sync.Mutex
+	last *log.Checkpoint
+}
+
+func (c *rfc6962LogClient) GetLogType() string {
+	return "rfc6962"
+}
+
+func (c *rfc6962LogClient) GetURL() string {
+	return c.url
+}
+
+func (c *rfc6962LogClient) GetOrigin() string {
+	return c.origin
+}
+
+func (c *rfc6962LogClient) GetVerifier() note.Verifier {
+	return c.verifier
+}
+
+// get fetches the JSON response of the API method at path into v.
+func (c *rfc6962LogClient) get(path string, v any) error {
+	b, err := c.fetcher(context.Background(), path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", path, err)
+	}
+	return nil
+}
+
+// GetCheckpoint fetches the log's signed tree head and converts it into a
+// checkpoint, with the tree head signature as its note signature. Each tree
+// head is checked to be consistent with the largest one seen before it.
+func (c *rfc6962LogClient) GetCheckpoint() (*model.Checkpoint, error) {
+	var sth struct {
+		TreeSize          uint64 `json:"tree_size"`
+		Timestamp         uint64 `json:"timestamp"`
+		SHA256RootHash    []byte `json:"sha256_root_hash"`
+		TreeHeadSignature []byte `json:"tree_head_signature"`
+	}
+	if err := c.get("ct/v1/get-sth", &sth); err != nil {
+		return nil, err
+	}
+	// The signature is a TLS digitally-signed struct: two bytes of
+	// algorithms and a length prefixed signature.
+	if len(sth.TreeHeadSignature) < 4 {
+		return nil, fmt.Errorf("tree head signature has %d bytes", len(sth.TreeHeadSignature))
+	}
+	sig := binary.BigEndian.AppendUint32(nil, c.verifier.KeyHash())
+	sig = binary.BigEndian.AppendUint64(sig, sth.Timestamp)
+	sig = append(sig, sth.TreeHeadSignature...)
+	raw := fmt.Appendf(nil, "%s\n%d\n%s\n\n— %s %s\n",
+		c.origin, sth.TreeSize, base64.StdEncoding.EncodeToString(sth.SHA256RootHash), c.verifier.Name(), base64.StdEncoding.EncodeToString(sig))
+	cp, _, n, err := log.ParseCheckpoint(raw, c.origin, c.verifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkConsistency(cp); err != nil {
+		return nil, err
+	}
+	return &model.Checkpoint{
+		Checkpoint: cp,
+		Raw:        raw,
+		Note:       n,
+	}, nil
+}
+
+// checkConsistency proves that cp and the largest tree head seen are
+// views of the same log, remembering cp if it's larger. Logs may serve
+// tree heads older than ones they served before, so cp can be smaller.
+func (c *rfc6962LogClient) checkConsistency(cp *log.Checkpoint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	last := c.last
+	if last == nil || last.Size == 0 {
+		c.last = cp
+		return nil
+	}
+	older, newer := last, cp
+	if cp.Size < last.Size {
+		older, newer = cp, last
+	}
+	if older.Size == newer.Size {
+		if !bytes.Equal(older.Hash, newer.Hash) {
+			return fmt.Errorf("tree heads of size %d have different root hashes %x and %x", cp.Size, last.Hash, cp.Hash)
+		}
+		return nil
+	}
+	if older.Size > 0 {
+		var resp struct {
+			Consistency [][]byte `json:"consistency"`
+		}
+		if err := c.get(fmt.Sprintf("ct/v1/get-sth-consistency?first=%d&second=%d", older.Size, newer.Size), &resp); err != nil {
+			return err
+		}
+		if err := proof.VerifyConsistency(rfc6962.DefaultHasher, older.Size, newer.Size, resp.Consistency, older.Hash, newer.Hash); err != nil {
+			return fmt.Errorf("tree head of size %d is not consistent with size %d: %w", newer.Size, older.Size, err)
+		}
+	}
+	c.last = newer
+	return nil
+}
+
+func (c *rfc6962LogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error) {
+	if checkpoint == nil {
+		return nil, errors.New("checkpoint is nil")
+	}
+	if index >= checkpoint.Size {
+		return nil, fmt.Errorf("index %d out of bounds for checkpoint size %d", index, checkpoint.Size)
+	}
+	// Leaves are read 256 at a time, like the bundles of tiled logs, so
+	// that consecutive leaves are read with a single request or a few.
+	n, width := index/256, min(256, checkpoint.Size-index/256*256)
+	batch, err := c.bundles.get(n, width, func() ([][]byte, error) {
+		return c.getEntries(n*256, n*256+width)
+	})
+	if err != nil {
+		return nil, err
+	}
+	input := batch[2*(index%256)]
+	entry := batch[2*(index%256)+1]
+
+	p := &model.InclusionProof{
+		Index:    index,
+		TreeSize: checkpoint.Size,
+		LeafHash: rfc6962.DefaultHasher.HashLeaf(input),
+	}
+	if checkpoint.Size > 1 {
+		var resp struct {
+			LeafIndex uint64   `json:"leaf_index"`
+			AuditPath [][]byte `json:"audit_path"`
+		}
+		path := fmt.Sprintf("ct/v1/get-proof-by-hash?hash=%s&tree_size=%d", url.QueryEscape(base64.StdEncoding.EncodeToString(p.LeafHash)), checkpoint.Size)
+		if err := c.get(path, &resp); err != nil {
+			return &model.Leaf{Contents: entry, Index: index, LeafHash: p.LeafHash}, err
+		}
+		// A certificate logged more than once has a proof for each copy,
+		// and the log may return any of them. That only proves that some
+		// copy is in the tree, so the leaf is proven at its own index with
+		// get-entry-and-proof instead.
+		if resp.LeafIndex != index {
+			hashes, err := c.entryProof(checkpoint.Size, index)
+			if err != nil {
+				return &model.Leaf{Contents: entry, Index: index, LeafHash: p.LeafHash}, err
+			}
+			resp.AuditPath = hashes
+		}
+		p.Hashes = resp.AuditPath
+	}
+	return verifyLeaf(checkpoint, entry, p)
+}
+
+// entryProof fetches the inclusion proof of the leaf at index in the tree of
+// the given size with get-entry-and-proof.
+func (c *rfc6962LogClient) entryProof(size, index uint64) ([][]byte, error) {
+	var resp struct {
+		AuditPath [][]byte `json:"audit_path"`
+	}
+	if err := c.get(fmt.Sprintf("ct/v1/get-entry-and-proof?leaf_index=%d&tree_size=%d", index, size), &resp); err != nil {
+		return nil, err
+	}
+	return resp.AuditPath, nil
+}
+
+// getEntries reads the leaves [start, end) with get-entries, which may
+// return fewer leaves than asked for. Each leaf is returned as its
+// MerkleTreeLeaf followed by its entry.
+func (c *rfc6962LogClient) getEntries(start, end uint64) ([][]byte, error) {
+	var leaves [][]byte
+	for i := start; i < end; {
+		var resp struct {
+			Entries []struct {
+				LeafInput []byte `json:"leaf_input"`
+				ExtraData []byte `json:"extra_data"`
+			} `json:"entries"`
+		}
+		if err := c.get(fmt.Sprintf("ct/v1/get-entries?start=%d&end=%d", i, end-1), &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Entries) == 0 || uint64(len(resp.Entries)) > end-i {
+			return nil, fmt.Errorf("get-entries from %d to %d returned %d entries", i, end-1, len(resp.Entries))
+		}
+		for _, e := range resp.Entries {
+			entry, err := parseRFC6962Entry(e.LeafInput, e.ExtraData)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i, err)
+			}
+			leaves = append(leaves, e.LeafInput, entry)
+			i++
+		}
+	}
+	return leaves, nil
+}
+
+// parseRFC6962Entry parses the MerkleTreeLeaf and extra data of an entry
+// returned by get-entries, and returns it as a JSON encoded log entry, the
+// same as leaves of static CT logs. The full precertificate is in the extra
+// data, along with the chain, which is kept as its fingerprints.
+func parseRFC6962Entry(leafInput, extraData []byte) ([]byte, error) {
+	e := &sunlight.LogEntry{RFC6962ArchivalLeaf: true}
+	s := cryptobyte.String(leafInput)
+	var version, leafType uint8
+	var entryType uint16
+	var timestamp uint64
+	var cert, extensions cryptobyte.String
+	if !s.ReadUint8(&version) || !s.ReadUint8(&leafType) || !s.ReadUint64(&timestamp) || !s.ReadUint16(&entryType) {
+		return nil, errors.New("truncated leaf_input")
+	}
+	if version != 0 || leafType != 0 {
+		return nil, fmt.Errorf("unknown leaf version %d or type %d", version, leafType)
+	}
+	e.Timestamp = int64(timestamp)
+	switch entryType {
+	case 0:
+	case 1:
+		e.IsPrecert = true
+		if !s.CopyBytes(e.IssuerKeyHash[:]) {
+			return nil, errors.New("truncated leaf_input")
+		}
+	default:
+		return nil, fmt.Errorf("unknown entry type %d", entryType)
+	}
+	if !s.ReadUint24LengthPrefixed(&cert) || !s.ReadUint16LengthPrefixed(&extensions) || !s.Empty() {
+		return nil, errors.New("malformed leaf_input")
+	}
+	e.Certificate = cert
+
+	x := cryptobyte.String(extraData)
+	if e.IsPrecert {
+		var precert cryptobyte.String
+		if !x.ReadUint24LengthPrefixed(&precert) {
+			return nil, errors.New("malformed extra_data")
+		}
+		e.PreCertificate = precert
+	}
+	var chain cryptobyte.String
+	if !x.ReadUint24LengthPrefixed(&chain) || !x.Empty() {
+		return nil, errors.New("malformed extra_data")
+	}
+	for !chain.Empty() {
+		var c cryptobyte.String
+		if !chain.ReadUint24LengthPrefixed(&c) {
+			return nil, errors.New("malformed certificate chain")
+		}
+		e.ChainFingerprints = append(e.ChainFingerprints, sha256.Sum256(c))
+	}
+	return json.Marshal(e)
+}
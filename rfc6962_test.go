@@ -0,0 +1,305 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"filippo.io/sunlight"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+// testRFC6962Log is an in-process stand-in for a CT log's JSON API.
+type testRFC6962Log struct {
+	t    *testing.T
+	priv *ecdsa.PrivateKey
+
+	mu sync.Mutex
+	// leaves holds each leaf's leaf_input and extra_data, and hashes the
+	// stored hashes of the tree of every leaf.
+	leaves [][2][]byte
+	hashes []tlog.Hash
+	// size is the size of the tree head served, and badRoot makes it
+	// serve a wrong root hash.
+	size    int64
+	badRoot bool
+	// noEntryProof makes get-entry-and-proof fail.
+	noEntryProof bool
+}
+
+func newTestRFC6962Log(t *testing.T) (*testRFC6962Log, *httptest.Server, string) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &testRFC6962Log{t: t, priv: priv}
+	srv := httptest.NewServer(l)
+	t.Cleanup(srv.Close)
+	return l, srv, base64.StdEncoding.EncodeToString(der)
+}
+
+// add appends a leaf to the log, and grows the tree head to include it.
+func (l *testRFC6962Log) add(leafInput, extraData []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := int64(len(l.leaves))
+	hashes, err := tlog.StoredHashes(n, leafInput, l)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	l.hashes = append(l.hashes, hashes...)
+	l.leaves = append(l.leaves, [2][]byte{leafInput, extraData})
+	l.size = n + 1
+}
+
+func (l *testRFC6962Log) ReadHashes(indexes []int64) ([]tlog.Hash, error) {
+	out := make([]tlog.Hash, len(indexes))
+	for i, x := range indexes {
+		out[i] = l.hashes[x]
+	}
+	return out, nil
+}
+
+func (l *testRFC6962Log) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	q := r.URL.Query()
+	arg := func(name string) int64 {
+		v, _ := strconv.ParseInt(q.Get(name), 10, 64)
+		return v
+	}
+	var resp any
+	switch r.URL.Path {
+	case "/ct/v1/get-sth":
+		root, err := tlog.TreeHash(l.size, l)
+		if err != nil {
+			l.t.Fatal(err)
+		}
+		if l.badRoot {
+			root[0] ^= 1
+		}
+		sig, err := signSTH(l.priv, "", uint64(l.size), root, 1700000000000)
+		if err != nil {
+			l.t.Fatal(err)
+		}
+		resp = map[string]any{"tree_size": l.size, "timestamp": 1700000000000, "sha256_root_hash": root[:], "tree_head_signature": sig}
+	case "/ct/v1/get-entries":
+		// Like real logs, return fewer entries than asked for.
+		start, end := arg("start"), min(arg("end"), arg("start")+2, int64(len(l.leaves))-1)
+		var entries []map[string][]byte
+		for i := start; i <= end; i++ {
+			entries = append(entries, map[string][]byte{"leaf_input": l.leaves[i][0], "extra_data": l.leaves[i][1]})
+		}
+		resp = map[string]any{"entries": entries}
+	case "/ct/v1/get-proof-by-hash":
+		h, _ := base64.StdEncoding.DecodeString(q.Get("hash"))
+		for i, leaf := range l.leaves {
+			if string(rfc6962.DefaultHasher.HashLeaf(leaf[0])) != string(h) {
+				continue
+			}
+			p, err := tlog.ProveRecord(arg("tree_size"), int64(i), l)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			resp = map[string]any{"leaf_index": i, "audit_path": recordProofHashes(p)}
+			break
+		}
+		if resp == nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	case "/ct/v1/get-entry-and-proof":
+		if l.noEntryProof {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		i := arg("leaf_index")
+		p, err := tlog.ProveRecord(arg("tree_size"), i, l)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp = map[string]any{"leaf_input": l.leaves[i][0], "extra_data": l.leaves[i][1], "audit_path": recordProofHashes(p)}
+	case "/ct/v1/get-sth-consistency":
+		p, err := tlog.ProveTree(arg("second"), arg("first"), l)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hashes := make([][]byte, len(p))
+		for i := range p {
+			hashes[i] = p[i][:]
+		}
+		resp = map[string]any{"consistency": hashes}
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		l.t.Error(err)
+	}
+}
+
+// testRFC6962Entry returns the leaf_input and extra_data of an entry for
+// cert, as a precertificate if precert is set.
+func testRFC6962Entry(cert []byte, precert bool, timestamp int64) ([]byte, []byte) {
+	e := &sunlight.LogEntry{Certificate: cert, IsPrecert: precert, Timestamp: timestamp, RFC6962ArchivalLeaf: true}
+	var extra cryptobyte.Builder
+	if precert {
+		// The TBSCertificate isn't parsed, so stand in for it.
+		e.Certificate = []byte("tbs")
+		e.IssuerKeyHash = sha256.Sum256([]byte("issuer"))
+		extra.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(cert) })
+	}
+	extra.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("issuer")) })
+	})
+	return e.MerkleTreeLeaf(), extra.BytesOrPanic()
+}
+
+func TestRFC6962LogClient(t *testing.T) {
+	const origin = "ct.example.com/2026h1"
+	l, srv, vkey := newTestRFC6962Log(t)
+	cert := generateSelfSignedCert(t)
+	for i := range 7 {
+		l.add(testRFC6962Entry(cert, i%2 == 1, int64(1000+i)))
+	}
+	client, err := newRFC6962LogClient(srv.URL, origin, vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, err := client.GetCheckpoint()
+	if err != nil {
+		t.Fatalf("GetCheckpoint: %v", err)
+	}
+	if cp.Origin != origin || cp.Size != 7 {
+		t.Fatalf("checkpoint = %q at size %d, want %q at 7", cp.Origin, cp.Size, origin)
+	}
+	if len(cp.Note.Sigs) != 1 {
+		t.Errorf("checkpoint has %d verified signatures, want 1", len(cp.Note.Sigs))
+	}
+
+	for i := range uint64(7) {
+		leaf, err := client.GetLeaf(cp, i)
+		if err != nil {
+			t.Fatalf("GetLeaf(%d): %v", i, err)
+		}
+		if !leaf.Verified || leaf.Index != i {
+			t.Errorf("leaf %d: verified %v at index %d", i, leaf.Verified, leaf.Index)
+		}
+		var e sunlight.LogEntry
+		if err := json.Unmarshal(leaf.Contents, &e); err != nil {
+			t.Fatalf("leaf %d: %v", i, err)
+		}
+		if e.IsPrecert != (i%2 == 1) || e.Timestamp != int64(1000+i) || len(e.ChainFingerprints) != 1 || e.ChainFingerprints[0] != sha256.Sum256([]byte("issuer")) {
+			t.Errorf("leaf %d = %+v", i, e)
+		}
+		if got := client.FormatLeaf(leaf.Contents); !strings.Contains(got, "Subject: CN=woodpecker.test") {
+			t.Errorf("FormatLeaf(leaf %d) = %q, want the certificate", i, got)
+		}
+	}
+
+	t.Run("consistent growth", func(t *testing.T) {
+		l.add(testRFC6962Entry(cert, false, 2000))
+		cp, err := client.GetCheckpoint()
+		if err != nil || cp.Size != 8 {
+			t.Fatalf("GetCheckpoint after growth = %v, %v; want size 8", cp, err)
+		}
+		// An older tree head must be consistent too.
+		l.mu.Lock()
+		l.size = 3
+		l.mu.Unlock()
+		if _, err := client.GetCheckpoint(); err != nil {
+			t.Errorf("GetCheckpoint of an older tree head: %v", err)
+		}
+	})
+
+	t.Run("inconsistent", func(t *testing.T) {
+		l.add(testRFC6962Entry(cert, false, 3000))
+		l.mu.Lock()
+		l.badRoot = true
+		l.mu.Unlock()
+		defer func() {
+			l.mu.Lock()
+			l.badRoot = false
+			l.mu.Unlock()
+		}()
+		// The bad root is signed, so only the consistency proof catches it.
+		if _, err := client.GetCheckpoint(); err == nil || !strings.Contains(err.Error(), "not consistent") {
+			t.Errorf("GetCheckpoint of an inconsistent tree head = %v, want consistency error", err)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		// The log proves a leaf by its hash, so gives the proof of the first
+		// copy of a leaf for the second, which is then proven at its own
+		// index.
+		l.add(testRFC6962Entry(cert, false, 1000))
+		cp, err := client.GetCheckpoint()
+		if err != nil {
+			t.Fatalf("GetCheckpoint: %v", err)
+		}
+		leaf, err := client.GetLeaf(cp, cp.Size-1)
+		if err != nil {
+			t.Fatalf("GetLeaf of a duplicate: %v", err)
+		}
+		if !leaf.Verified || leaf.Index != cp.Size-1 || leaf.Proof.Index != cp.Size-1 {
+			t.Errorf("duplicate leaf: verified %v at index %d, proven at %d; want verified at %d", leaf.Verified, leaf.Index, leaf.Proof.Index, cp.Size-1)
+		}
+
+		// Without a proof at its own index, the leaf isn't verified.
+		l.mu.Lock()
+		l.noEntryProof = true
+		l.mu.Unlock()
+		leaf, err = client.GetLeaf(cp, cp.Size-1)
+		if err == nil || leaf == nil || leaf.Verified || string(leaf.Contents) == "" {
+			t.Errorf("GetLeaf of a duplicate without get-entry-and-proof = %+v, %v; want the unverified leaf and an error", leaf, err)
+		}
+	})
+}
+
+func TestRFC6962LogClientBadSignature(t *testing.T) {
+	l, srv, _ := newTestRFC6962Log(t)
+	l.add(testRFC6962Entry(generateSelfSignedCert(t), false, 1))
+	_, _, otherKey := newTestRFC6962Log(t)
+	client, err := newRFC6962LogClient(srv.URL, "ct.example.com/log", otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetCheckpoint(); err == nil {
+		t.Error("GetCheckpoint with the wrong key succeeded, want error")
+	}
+}
+
+func TestParseRFC6962EntryMalformed(t *testing.T) {
+	cert := generateSelfSignedCert(t)
+	leafInput, extraData := testRFC6962Entry(cert, true, 1)
+	for name, tc := range map[string][2][]byte{
+		"truncated leaf":  {leafInput[:len(leafInput)-1], extraData},
+		"trailing data":   {append(leafInput[:len(leafInput):len(leafInput)], 0), extraData},
+		"no precert":      {leafInput, extraData[3+len(cert):]},
+		"truncated chain": {leafInput, extraData[:len(extraData)-1]},
+	} {
+		if _, err := parseRFC6962Entry(tc[0], tc[1]); err == nil {
+			t.Errorf("%s: parseRFC6962Entry succeeded, want error", name)
+		}
+	}
+}
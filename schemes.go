@@ -0,0 +1,288 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// objectURL returns the HTTP URL of an object in a gs:// or s3:// bucket,
+// using the path-style API of the --gcs_endpoint or --s3_endpoint. Other
+// URLs are returned unchanged.
+func objectURL(u *url.URL) (*url.URL, error) {
+	var endpoint string
+	switch u.Scheme {
+	case "gs":
+		endpoint = *gcsEndpoint
+	case "s3":
+		endpoint = *s3Endpoint
+	default:
+		return u, nil
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("no bucket in %s", u.Redacted())
+	}
+	e, err := url.Parse(endpoint)
+	if err != nil || e.Host == "" {
+		return nil, fmt.Errorf("invalid %s endpoint %q", u.Scheme, endpoint)
+	}
+	o := e.JoinPath(u.Host, u.Path)
+	o.RawQuery = u.RawQuery
+	return o, nil
+}
+
+// readObject reads an object from a gs:// or s3:// bucket.
+func readObject(ctx context.Context, hc *http.Client, u *url.URL) ([]byte, error) {
+	o, err := objectURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return readHTTP(ctx, hc, o)
+}
+
+// readArchive reads a file from a tar or zip snapshot of a log. The URL path
+// is the path of the archive followed by the path of the file within it,
+// e.g. tar+file:///tmp/log.tar.gz/tile/0/000.
+func readArchive(_ context.Context, _ *http.Client, u *url.URL) ([]byte, error) {
+	archivePath, name, err := splitArchivePath(u.Path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	return a.read(name)
+}
+
+// splitArchivePath splits p into the path of an archive file, recognised by
+// its extension, and the path within it.
+func splitArchivePath(p string) (string, string, error) {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if isArchiveName(part) {
+			return strings.Join(parts[:i+1], "/"), strings.Join(parts[i+1:], "/"), nil
+		}
+	}
+	return "", "", fmt.Errorf("no .tar, .tar.gz, .tgz or .zip archive in path %q", p)
+}
+
+func isArchiveName(name string) bool {
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// archive is an opened tar or zip snapshot of a log.
+type archive struct {
+	// prefix is the directory within the archive that holds the log, which
+	// is where its checkpoint is.
+	prefix string
+	// Exactly one of files and zipFiles is set.
+	files    map[string]func() ([]byte, error)
+	zipFiles map[string]*zip.File
+}
+
+var (
+	archivesMu sync.Mutex
+	archives   = make(map[string]*archive)
+)
+
+// openArchive opens and indexes the archive at p, or returns it if it was
+// already opened.
+func openArchive(p string) (*archive, error) {
+	archivesMu.Lock()
+	defer archivesMu.Unlock()
+	if a, ok := archives[p]; ok {
+		return a, nil
+	}
+	var (
+		a   *archive
+		err error
+	)
+	if strings.HasSuffix(p, ".zip") {
+		a, err = openZip(p)
+	} else {
+		a, err = openTar(p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening archive %s: %w", p, err)
+	}
+	a.prefix = a.logPrefix()
+	archives[p] = a
+	return a, nil
+}
+
+func openZip(p string) (*archive, error) {
+	r, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, err
+	}
+	// The reader stays open for as long as the archive is browsed.
+	a := &archive{zipFiles: make(map[string]*zip.File)}
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() {
+			a.zipFiles[cleanArchiveName(f.Name)] = f
+		}
+	}
+	return a, nil
+}
+
+// openTar indexes a tar archive. Uncompressed archives are read from disk
+// as files are needed, but compressed ones can't be seeked and so are read
+// into memory.
+func openTar(p string) (*archive, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	compressed := strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".tgz")
+	var r io.Reader = f
+	if compressed {
+		defer func() { _ = f.Close() }()
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	}
+	cr := &offsetReader{r: r}
+	tr := tar.NewReader(cr)
+	a := &archive{files: make(map[string]func() ([]byte, error))}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := cleanArchiveName(h.Name)
+		if compressed {
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			a.files[name] = func() ([]byte, error) { return b, nil }
+			continue
+		}
+		// The header has been read, so the file starts at the current
+		// offset.
+		off, size := cr.n, h.Size
+		a.files[name] = func() ([]byte, error) { return io.ReadAll(io.NewSectionReader(f, off, size)) }
+	}
+	return a, nil
+}
+
+// offsetReader counts the bytes read, to find offsets within a tar.
+type offsetReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *offsetReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func cleanArchiveName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// logPrefix returns the directory holding the log's checkpoint, so that
+// archives of a directory, rather than of its contents, can be opened.
+func (a *archive) logPrefix() string {
+	best := ""
+	found := false
+	for _, name := range a.names() {
+		base := path.Base(name)
+		if base != "checkpoint" && base != "latest" {
+			continue
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		if !found || len(dir) < len(best) {
+			best, found = dir, true
+		}
+	}
+	return best
+}
+
+func (a *archive) names() []string {
+	var names []string
+	for n := range a.files {
+		names = append(names, n)
+	}
+	for n := range a.zipFiles {
+		names = append(names, n)
+	}
+	return names
+}
+
+// read returns the contents of the log file name, relative to the log's
+// directory in the archive. Missing files are reported as os.ErrNotExist,
+// as they are for the file scheme.
+func (a *archive) read(name string) ([]byte, error) {
+	name = cleanArchiveName(path.Join(a.prefix, name))
+	if open, ok := a.files[name]; ok {
+		return open()
+	}
+	if f, ok := a.zipFiles[name]; ok {
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = r.Close() }()
+		return io.ReadAll(r)
+	}
+	return nil, os.ErrNotExist
+}
+
+// archiveTransport serves the files of an archive over HTTP, so that
+// clients which only speak HTTP can read a snapshot. Every URL path is
+// treated as a path within the archive.
+type archiveTransport struct {
+	a *archive
+}
+
+func (t *archiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b, err := t.a.read(strings.TrimPrefix(req.URL.Path, "/"))
+	resp := &http.Response{
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Request:    req,
+	}
+	switch {
+	case err == nil:
+		resp.StatusCode = http.StatusOK
+		resp.ContentLength = int64(len(b))
+	case os.IsNotExist(err):
+		resp.StatusCode = http.StatusNotFound
+	default:
+		return nil, err
+	}
+	resp.Status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	return resp, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeTestArchive writes files into an archive at p, under dir, in the
+// format given by p's extension.
+func writeTestArchive(t *testing.T, p, dir string, files map[string][]byte) {
+	t.Helper()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	if strings.HasSuffix(p, ".zip") {
+		zw := zip.NewWriter(&buf)
+		for _, name := range names {
+			w, err := zw.Create(dir + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write(files[name])
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		var w io.Writer = &buf
+		var zw *gzip.Writer
+		if strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".tgz") {
+			zw = gzip.NewWriter(&buf)
+			w = zw
+		}
+		tw := tar.NewWriter(w)
+		for _, name := range names {
+			if err := tw.WriteHeader(&tar.Header{Name: dir + name, Mode: 0o644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}); err != nil {
+				t.Fatal(err)
+			}
+			_, _ = tw.Write(files[name])
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if zw != nil {
+			_ = zw.Close()
+		}
+	}
+	if err := os.WriteFile(p, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveSnapshots(t *testing.T) {
+	const origin = "example.com/snapshot"
+	files, vkey := newTestLog(t, "tiles", origin, []string{"zero", "one", "two", "three", "four"})
+	for _, tc := range []struct {
+		name, scheme, dir string
+	}{
+		{name: "log.tar", scheme: "tar+file"},
+		{name: "log.tar.gz", scheme: "tar+file", dir: "snapshot/"},
+		{name: "log.tgz", scheme: "tar+file", dir: "./snapshot/"},
+		{name: "log.zip", scheme: "zip+file", dir: "snapshot/"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), tc.name)
+			writeTestArchive(t, p, tc.dir, files)
+
+			client, err := newTLogTilesLogClient(tc.scheme+"://"+p, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cp, err := client.GetCheckpoint()
+			if err != nil {
+				t.Fatalf("GetCheckpoint: %v", err)
+			}
+			if cp.Size != 5 {
+				t.Errorf("checkpoint size = %d, want 5", cp.Size)
+			}
+			for i, want := range []string{"zero", "one", "two", "three", "four"} {
+				l, err := client.GetLeaf(cp, uint64(i))
+				if err != nil {
+					t.Fatalf("GetLeaf(%d): %v", i, err)
+				}
+				if string(l.Contents) != want || !l.Verified {
+					t.Errorf("GetLeaf(%d) = %q (verified %v), want verified %q", i, l.Contents, l.Verified, want)
+				}
+			}
+
+			u, _ := url.Parse(tc.scheme + "://" + p + "/missing")
+			if _, err := readArchive(t.Context(), nil, u); !os.IsNotExist(err) {
+				t.Errorf("reading a missing file = %v, want not exist", err)
+			}
+		})
+	}
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	for _, tc := range []struct {
+		p, archive, name string
+	}{
+		{p: "/tmp/log.tar/checkpoint", archive: "/tmp/log.tar", name: "checkpoint"},
+		{p: "/tmp/log.tar.gz/tile/0/000", archive: "/tmp/log.tar.gz", name: "tile/0/000"},
+		{p: "/tmp/log.zip/", archive: "/tmp/log.zip", name: ""},
+	} {
+		archive, name, err := splitArchivePath(tc.p)
+		if err != nil || archive != tc.archive || name != tc.name {
+			t.Errorf("splitArchivePath(%q) = %q, %q, %v; want %q, %q", tc.p, archive, name, err, tc.archive, tc.name)
+		}
+	}
+	if _, _, err := splitArchivePath("/tmp/log/checkpoint"); err == nil {
+		t.Error("splitArchivePath of a path without an archive succeeded, want error")
+	}
+}
+
+func TestBucketSchemes(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Path)
+		_, _ = fmt.Fprint(w, "checkpoint")
+	}))
+	defer srv.Close()
+	oldGCS, oldS3 := *gcsEndpoint, *s3Endpoint
+	defer func() { *gcsEndpoint, *s3Endpoint = oldGCS, oldS3 }()
+	*gcsEndpoint, *s3Endpoint = srv.URL, srv.URL+"/s3"
+
+	for _, lr := range []string{"gs://my-bucket/logs/a/", "s3://other-bucket/b/"} {
+		root, _ := url.Parse(lr)
+		fetch := newFetcher(root, httpClient)
+		b, err := fetch(t.Context(), "checkpoint")
+		if err != nil {
+			t.Fatalf("fetching from %s: %v", lr, err)
+		}
+		if string(b) != "checkpoint" {
+			t.Errorf("got %q", b)
+		}
+	}
+	want := []string{"/my-bucket/logs/a/checkpoint", "/s3/other-bucket/b/checkpoint"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("requested %q, want %q", got, want)
+	}
+
+	u, _ := url.Parse("s3:///checkpoint")
+	if _, err := objectURL(u); err == nil {
+		t.Error("objectURL without a bucket succeeded, want error")
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mhutchinson/woodpecker/model"
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/rfc6962"
+	serverless_client "github.com/transparency-dev/serverless-log/client"
+	"golang.org/x/mod/sumdb/note"
+	"golang.org/x/sync/singleflight"
+)
+
+// sigsumLeafSize is the size of a Sigsum leaf: a checksum, the submitter's
+// signature of it, and the hash of the submitter's key.
+const sigsumLeafSize = 32 + ed25519.SignatureSize + 32
+
+func newSigsumLogClient(lr string, origin string, vkey string) (logClient, error) {
+	if !strings.HasSuffix(lr, "/") {
+		lr = lr + "/"
+	}
+	logRoot, err := url.Parse(lr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL %q: %w", lr, err)
+	}
+	pub, err := hex.DecodeString(vkey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid verifier key %q: expected a hex Ed25519 public key", vkey)
+	}
+	// Sigsum tree heads are signed as checkpoints with this origin.
+	keyHash := sha256.Sum256(pub)
+	sigsumOrigin := "sigsum.org/v1/tree/" + hex.EncodeToString(keyHash[:])
+	if len(origin) == 0 {
+		origin = sigsumOrigin
+	} else if origin != sigsumOrigin {
+		return nil, fmt.Errorf("origin of the Sigsum log with this key is %q, not %q", sigsumOrigin, origin)
+	}
+	noteKey, err := note.NewEd25519VerifierKey(origin, pub)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := note.NewVerifier(noteKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verifier: %w", err)
+	}
+	fetcher, err := newLogFetcher(origin, logRoot)
+	if err != nil {
+		return nil, err
+	}
+	return &sigsumLogClient{
+		url:      lr,
+		origin:   origin,
+		verifier: verifier,
+		fetcher:  fetcher,
+	}, nil
+}
+
+// sigsumLogClient reads Sigsum logs (https://sigsum.org) through their
+// HTTP API.
+type sigsumLogClient struct {
+	url      string
+	origin   string
+	verifier note.Verifier
+	fetcher  serverless_client.Fetcher
+	bundles  bundleCache
+	// witnesses are the witnesses of the log's witness policy, by the hash
+	// of their key, which is how tree heads identify their cosignatures.
+	witnesses map[[32]byte]*policyWitness
+
+	sfg singleflight.Group
+}
+
+// useWitnessPolicy names the cosignatures on the log's tree heads by the
+// witnesses in p, so that they can be verified.
+func (c *sigsumLogClient) useWitnessPolicy(p *witnessPolicy) {
+	c.witnesses = make(map[[32]byte]*policyWitness)
+	if p == nil {
+		return
+	}
+	for _, w := range p.witnesses {
+		if w.key != nil {
+			c.witnesses[sha256.Sum256(w.key)] = w
+		}
+	}
+}
+
+func (c *sigsumLogClient) GetLogType() string {
+	return "sigsum"
+}
+
+func (c *sigsumLogClient) GetURL() string {
+	return c.url
+}
+
+func (c *sigsumLogClient) GetOrigin() string {
+	return c.origin
+}
+
+func (c *sigsumLogClient) GetVerifier() note.Verifier {
+	return c.verifier
+}
+
+// get fetches the response of the API endpoint at path, which is a list of
+// key=value lines, returning the values of each key.
+func (c *sigsumLogClient) get(path string) (map[string][]string, error) {
+	b, err := c.fetcher(context.Background(), path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	values := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid line %q", path, line)
+		}
+		values[k] = append(values[k], v)
+	}
+	return values, nil
+}
+
+// GetCheckpoint fetches the log's tree head and its cosignatures, and
+// converts them into a checkpoint. Only the log's signature decides whether
+// the tree head opens. Cosignatures from the witnesses in the log's witness
+// policy are verified separately, and left out if they don't verify, and
+// those from other witnesses are kept unverified.
+func (c *sigsumLogClient) GetCheckpoint() (*model.Checkpoint, error) {
+	th, err := c.treeHead()
+	if err != nil {
+		return nil, err
+	}
+	return th.checkpoint, nil
+}
+
+// sigsumTreeHead is a tree head as a checkpoint, and the reasons for
+// leaving out any of its cosignatures.
+type sigsumTreeHead struct {
+	checkpoint *model.Checkpoint
+	invalid    []error
+}
+
+func (c *sigsumLogClient) treeHead() (*sigsumTreeHead, error) {
+	val, err, _ := c.sfg.Do("checkpoint", func() (interface{}, error) {
+		th, err := c.get("get-tree-head")
+		if err != nil {
+			return nil, err
+		}
+		size, err := sigsumValue(th, "size")
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseUint(size, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q", size)
+		}
+		root, err := sigsumHex(th, "root_hash", 32)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := sigsumHex(th, "signature", ed25519.SignatureSize)
+		if err != nil {
+			return nil, err
+		}
+		text := fmt.Sprintf("%s\n%d\n%s\n", c.origin, n, base64.StdEncoding.EncodeToString(root))
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s\n— %s %s\n", text, c.origin, base64.StdEncoding.EncodeToString(append(binary.BigEndian.AppendUint32(nil, c.verifier.KeyHash()), sig...)))
+		var (
+			verifiers []note.Verifier
+			invalid   []error
+		)
+		for _, cs := range th["cosignature"] {
+			s, w, err := c.cosignature(cs)
+			if err != nil {
+				invalid = append(invalid, err)
+				continue
+			}
+			if w != nil {
+				// A bad cosignature only means that the witness didn't
+				// cosign the tree head, so it is left out rather than
+				// failing to open the note.
+				if !verifyCosignatureV1(w.key, text, s) {
+					invalid = append(invalid, fmt.Errorf("cosignature from %s doesn't verify", w.name))
+					continue
+				}
+				verifiers = append(verifiers, w.verifier)
+			}
+			fmt.Fprintf(&sb, "— %s %s\n", s.Name, s.Base64)
+		}
+		raw := []byte(sb.String())
+		cp, _, vn, err := log.ParseCheckpoint(raw, c.origin, c.verifier, dedupeVerifiers(verifiers)...)
+		if err != nil {
+			return nil, err
+		}
+		return &sigsumTreeHead{
+			checkpoint: &model.Checkpoint{
+				Checkpoint: cp,
+				Note:       vn,
+				Raw:        raw,
+			},
+			invalid: invalid,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*sigsumTreeHead), nil
+}
+
+// cosignature converts a tree head's cosignature, "KEY_HASH TIMESTAMP
+// SIGNATURE", into a note signature, along with the witness that made it if
+// the witness is in the policy. Signatures by the policy's witnesses are
+// named so that their verifiers match them. Other witnesses are named by
+// their key hash, and their cosignatures can't be verified.
+func (c *sigsumLogClient) cosignature(cs string) (note.Signature, *policyWitness, error) {
+	f := strings.Fields(cs)
+	if len(f) != 3 {
+		return note.Signature{}, nil, fmt.Errorf("invalid cosignature %q", cs)
+	}
+	kh, err := hex.DecodeString(f[0])
+	if err != nil || len(kh) != 32 {
+		return note.Signature{}, nil, fmt.Errorf("invalid cosignature key hash %q", f[0])
+	}
+	ts, err := strconv.ParseUint(f[1], 10, 64)
+	if err != nil {
+		return note.Signature{}, nil, fmt.Errorf("invalid cosignature timestamp %q", f[1])
+	}
+	sig, err := hex.DecodeString(f[2])
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return note.Signature{}, nil, fmt.Errorf("invalid cosignature %q", f[2])
+	}
+	name, hash := "sigsum-witness-"+f[0][:16], binary.BigEndian.Uint32(kh)
+	w := c.witnesses[[32]byte(kh)]
+	if w != nil {
+		name, hash = w.verifier.Name(), w.verifier.KeyHash()
+	}
+	b := binary.BigEndian.AppendUint32(nil, hash)
+	b = binary.BigEndian.AppendUint64(b, ts)
+	b = append(b, sig...)
+	return note.Signature{Name: name, Hash: hash, Base64: base64.StdEncoding.EncodeToString(b)}, w, nil
+}
+
+// GetCosignedCheckpoint returns the latest tree head with the cosignatures
+// which verified, and the reasons for leaving out the others.
+func (c *sigsumLogClient) GetCosignedCheckpoint() ([]byte, []error, error) {
+	th, err := c.treeHead()
+	if err != nil {
+		return nil, nil, err
+	}
+	return th.checkpoint.Raw, th.invalid, nil
+}
+
+func (c *sigsumLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error) {
+	if checkpoint == nil {
+		return nil, errors.New("checkpoint is nil")
+	}
+	if index >= checkpoint.Size {
+		return nil, fmt.Errorf("index %d out of bounds for checkpoint size %d", index, checkpoint.Size)
+	}
+	// Leaves are read 256 at a time, like the bundles of tiled logs, so
+	// that consecutive leaves are read with a single request or a few.
+	n, width := index/256, min(256, checkpoint.Size-index/256*256)
+	leaves, err := c.bundles.get(n, width, func() ([][]byte, error) {
+		return c.getLeaves(n*256, n*256+width)
+	})
+	if err != nil {
+		return nil, err
+	}
+	leaf := leaves[index%256]
+
+	p := &model.InclusionProof{
+		Index:    index,
+		TreeSize: checkpoint.Size,
+		LeafHash: rfc6962.DefaultHasher.HashLeaf(leaf),
+	}
+	if checkpoint.Size > 1 {
+		resp, err := c.get(fmt.Sprintf("get-inclusion-proof/%d/%x", checkpoint.Size, p.LeafHash))
+		if err != nil {
+			return &model.Leaf{Contents: leaf, Index: index, LeafHash: p.LeafHash}, err
+		}
+		got, err := sigsumValue(resp, "leaf_index")
+		if err != nil {
+			return &model.Leaf{Contents: leaf, Index: index, LeafHash: p.LeafHash}, err
+		}
+		// Sigsum logs don't add a leaf that they already have, so a proof
+		// for any other index means the log is misbehaving.
+		if got != strconv.FormatUint(index, 10) {
+			return &model.Leaf{Contents: leaf, Index: index, LeafHash: p.LeafHash}, fmt.Errorf("log returned a proof for leaf %s, not %d", got, index)
+		}
+		for _, h := range resp["node_hash"] {
+			b, err := hex.DecodeString(h)
+			if err != nil || len(b) != 32 {
+				return &model.Leaf{Contents: leaf, Index: index, LeafHash: p.LeafHash}, fmt.Errorf("invalid node hash %q", h)
+			}
+			p.Hashes = append(p.Hashes, b)
+		}
+	}
+	return verifyLeaf(checkpoint, leaf, p)
+}
+
+// getLeaves reads the leaves [start, end) with get-leaves, which may return
+// fewer leaves than asked for. Each leaf is returned as it's hashed: its
+// checksum, signature and key hash.
+func (c *sigsumLogClient) getLeaves(start, end uint64) ([][]byte, error) {
+	var leaves [][]byte
+	for i := start; i < end; {
+		resp, err := c.get(fmt.Sprintf("get-leaves/%d/%d", i, end))
+		if err != nil {
+			return nil, err
+		}
+		got := resp["leaf"]
+		if len(got) == 0 || uint64(len(got)) > end-i {
+			return nil, fmt.Errorf("get-leaves from %d to %d returned %d leaves", i, end, len(got))
+		}
+		for _, l := range got {
+			leaf, err := hex.DecodeString(strings.ReplaceAll(l, " ", ""))
+			if err != nil || len(leaf) != sigsumLeafSize || strings.Count(l, " ") != 2 {
+				return nil, fmt.Errorf("leaf %d: invalid leaf %q", i, l)
+			}
+			leaves = append(leaves, leaf)
+			i++
+		}
+	}
+	return leaves, nil
+}
+
+// FormatLeaf shows the parts of a Sigsum leaf.
+func (c *sigsumLogClient) FormatLeaf(leaf []byte) string {
+	v, err := c.LeafValues(leaf)
+	if err != nil {
+		return fmt.Sprintf("Failed to parse leaf: %v", err)
+	}
+	return fmt.Sprintf("Checksum: %s\nSignature: %s\nKey Hash: %s\n", v[0], v[1], v[2])
+}
+
+// LeafFields returns the names of the parts of a Sigsum leaf that are
+// exported as CSV.
+func (c *sigsumLogClient) LeafFields() []string {
+	return []string{"checksum", "signature", "key_hash"}
+}
+
+// LeafValues returns the LeafFields of a leaf, hex encoded.
+func (c *sigsumLogClient) LeafValues(leaf []byte) ([]string, error) {
+	if len(leaf) != sigsumLeafSize {
+		return nil, fmt.Errorf("leaf has %d bytes, want %d", len(leaf), sigsumLeafSize)
+	}
+	return []string{
+		hex.EncodeToString(leaf[:32]),
+		hex.EncodeToString(leaf[32 : 32+ed25519.SignatureSize]),
+		hex.EncodeToString(leaf[32+ed25519.SignatureSize:]),
+	}, nil
+}
+
+// sigsumValue returns the only value of key in a response.
+func sigsumValue(values map[string][]string, key string) (string, error) {
+	v := values[key]
+	if len(v) != 1 {
+		return "", fmt.Errorf("response has %d values of %s, want 1", len(v), key)
+	}
+	return v[0], nil
+}
+
+// sigsumHex returns the only value of key in a response, which is hex
+// encoding size bytes.
+func sigsumHex(values map[string][]string, key string, size int) ([]byte, error) {
+	v, err := sigsumValue(values, key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := hex.DecodeString(v)
+	if err != nil || len(b) != size {
+		return nil, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return b, nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+// testSigsumLog is an in-process stand-in for a Sigsum log's HTTP API.
+type testSigsumLog struct {
+	t         *testing.T
+	priv      ed25519.PrivateKey
+	origin    string
+	witnesses []ed25519.PrivateKey
+
+	mu     sync.Mutex
+	leaves [][]byte
+	hashes []tlog.Hash
+	// badCosig makes the first witness's cosignature fail to verify.
+	badCosig bool
+}
+
+func newTestSigsumLog(t *testing.T, witnesses int) (*testSigsumLog, *httptest.Server, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kh := sha256.Sum256(pub)
+	l := &testSigsumLog{t: t, priv: priv, origin: "sigsum.org/v1/tree/" + hex.EncodeToString(kh[:])}
+	for range witnesses {
+		_, w, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.witnesses = append(l.witnesses, w)
+	}
+	srv := httptest.NewServer(l)
+	t.Cleanup(srv.Close)
+	return l, srv, hex.EncodeToString(pub)
+}
+
+// add appends a leaf for msg, signed by a submitter, to the log.
+func (l *testSigsumLog) add(msg string) []byte {
+	_, submitter, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	checksum := sha256.Sum256([]byte(msg))
+	keyHash := sha256.Sum256(submitter.Public().(ed25519.PublicKey))
+	leaf := append(checksum[:], ed25519.Sign(submitter, append([]byte("sigsum.org/v1/tree-leaf\x00"), checksum[:]...))...)
+	leaf = append(leaf, keyHash[:]...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hashes, err := tlog.StoredHashes(int64(len(l.leaves)), leaf, l)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	l.hashes = append(l.hashes, hashes...)
+	l.leaves = append(l.leaves, leaf)
+	return leaf
+}
+
+func (l *testSigsumLog) ReadHashes(indexes []int64) ([]tlog.Hash, error) {
+	out := make([]tlog.Hash, len(indexes))
+	for i, x := range indexes {
+		out[i] = l.hashes[x]
+	}
+	return out, nil
+}
+
+func (l *testSigsumLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	size := int64(len(l.leaves))
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	arg := func(i int) int64 {
+		v, _ := strconv.ParseInt(parts[i], 10, 64)
+		return v
+	}
+	switch {
+	case parts[0] == "get-tree-head":
+		root, err := tlog.TreeHash(size, l)
+		if err != nil {
+			l.t.Fatal(err)
+		}
+		text := fmt.Sprintf("%s\n%d\n%s\n", l.origin, size, base64.StdEncoding.EncodeToString(root[:]))
+		fmt.Fprintf(w, "size=%d\nroot_hash=%x\nsignature=%x\n", size, root[:], ed25519.Sign(l.priv, []byte(text)))
+		for i, wit := range l.witnesses {
+			ts := 1700000000 + i
+			kh := sha256.Sum256(wit.Public().(ed25519.PublicKey))
+			sig := ed25519.Sign(wit, fmt.Appendf(nil, "cosignature/v1\ntime %d\n%s", ts, text))
+			if i == 0 && l.badCosig {
+				sig[0] ^= 1
+			}
+			fmt.Fprintf(w, "cosignature=%x %d %x\n", kh[:], ts, sig)
+		}
+	case parts[0] == "get-leaves" && len(parts) == 3:
+		// Like real logs, return fewer leaves than asked for.
+		for i := arg(1); i < min(arg(2), arg(1)+3, size); i++ {
+			leaf := l.leaves[i]
+			fmt.Fprintf(w, "leaf=%x %x %x\n", leaf[:32], leaf[32:96], leaf[96:])
+		}
+	case parts[0] == "get-inclusion-proof" && len(parts) == 3:
+		for i, leaf := range l.leaves {
+			if h := tlog.RecordHash(leaf); hex.EncodeToString(h[:]) != parts[2] {
+				continue
+			}
+			p, err := tlog.ProveRecord(arg(1), int64(i), l)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, "leaf_index=%d\n", i)
+			for _, h := range p {
+				fmt.Fprintf(w, "node_hash=%x\n", h[:])
+			}
+			return
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func TestSigsumLogClient(t *testing.T) {
+	l, srv, vkey := newTestSigsumLog(t, 2)
+	var leaves [][]byte
+	for i := range 5 {
+		leaves = append(leaves, l.add(fmt.Sprintf("artifact %d", i)))
+	}
+	c, err := newSigsumLogClient(srv.URL, "", vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := c.(*sigsumLogClient)
+	if client.GetOrigin() != l.origin {
+		t.Errorf("origin = %q, want %q", client.GetOrigin(), l.origin)
+	}
+	// Only the first witness is in the policy.
+	policy, err := parseWitnessPolicy(fmt.Appendf(nil, "log %s\nwitness w1 %x\nquorum w1\n", vkey, l.witnesses[0].Public()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.useWitnessPolicy(policy)
+
+	cp, err := client.GetCheckpoint()
+	if err != nil {
+		t.Fatalf("GetCheckpoint: %v", err)
+	}
+	if cp.Size != 5 {
+		t.Errorf("checkpoint size = %d, want 5", cp.Size)
+	}
+	if len(cp.Note.Sigs) != 2 || cp.Note.Sigs[1].Name != "w1" || len(cp.Note.UnverifiedSigs) != 1 {
+		t.Errorf("checkpoint signatures = %+v, unverified %+v; want the log and w1, and one unknown", cp.Note.Sigs, cp.Note.UnverifiedSigs)
+	}
+
+	for i := range uint64(5) {
+		leaf, err := client.GetLeaf(cp, i)
+		if err != nil {
+			t.Fatalf("GetLeaf(%d): %v", i, err)
+		}
+		if !leaf.Verified || string(leaf.Contents) != string(leaves[i]) {
+			t.Errorf("leaf %d: verified %v, contents %x", i, leaf.Verified, leaf.Contents)
+		}
+	}
+	want := fmt.Sprintf("Checksum: %x\nSignature: %x\nKey Hash: %x\n", leaves[2][:32], leaves[2][32:96], leaves[2][96:])
+	if got := client.FormatLeaf(leaves[2]); got != want {
+		t.Errorf("FormatLeaf = %q, want %q", got, want)
+	}
+
+	t.Run("witnessed", func(t *testing.T) {
+		msg := fetchWitnessed(nil, client, 1, policy.Verifiers(), policy)
+		if msg.witnessed == nil || msg.witnessed.Size != 5 {
+			t.Fatalf("witnessed = %v, want the tree head of size 5", msg.witnessed)
+		}
+		if msg.policy == nil || !msg.policy.satisfied {
+			t.Errorf("policy result = %+v, want satisfied", msg.policy)
+		}
+		if len(msg.distributors) != 1 || msg.distributors[0].name != "log" || msg.distributors[0].cosigs != 1 {
+			t.Errorf("sources = %+v, want the log with 1 cosignature", msg.distributors)
+		}
+	})
+
+	t.Run("bad cosignature", func(t *testing.T) {
+		// A cosignature from a witness in the policy which doesn't verify is
+		// left out, without failing the tree head, and shown as a problem
+		// with the log's cosignatures.
+		l.mu.Lock()
+		l.badCosig = true
+		l.mu.Unlock()
+		defer func() {
+			l.mu.Lock()
+			l.badCosig = false
+			l.mu.Unlock()
+		}()
+		cp, err := client.GetCheckpoint()
+		if err != nil {
+			t.Fatalf("GetCheckpoint with a cosignature that doesn't verify: %v", err)
+		}
+		if len(cp.Note.Sigs) != 1 || len(cp.Note.UnverifiedSigs) != 1 || strings.Contains(string(cp.Raw), "— w1 ") {
+			t.Errorf("checkpoint signatures = %+v, unverified %+v; want only the log, and the unknown witness", cp.Note.Sigs, cp.Note.UnverifiedSigs)
+		}
+
+		msg := fetchWitnessed(nil, client, 0, policy.Verifiers(), policy)
+		if msg.policy == nil || msg.policy.satisfied {
+			t.Errorf("policy result = %+v, want unsatisfied", msg.policy)
+		}
+		if len(msg.distributors) != 1 || msg.distributors[0].cosigs != 0 || len(msg.distributors[0].invalid) != 1 {
+			t.Fatalf("sources = %+v, want the log with no cosignatures and 1 invalid", msg.distributors)
+		}
+		m := NewModel([]string{"sigsum"}, map[string]logClient{"sigsum": client}, nil, nil, "sigsum", modelOptions{})
+		m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+		m.distResults = msg.distributors
+		m.renderWitnesses()
+		if got := m.witnessView.View(); !strings.Contains(got, "cosignature from w1 doesn't verify") {
+			t.Errorf("witness view doesn't show the bad cosignature:\n%s", got)
+		}
+	})
+}
+
+func TestNewSigsumLogClient(t *testing.T) {
+	pub := strings.Repeat("ab", 32)
+	if _, err := newSigsumLogClient("https://sigsum.example.com/", "example.com/log", pub); err == nil {
+		t.Error("newSigsumLogClient with the wrong origin succeeded, want error")
+	}
+	if _, err := newSigsumLogClient("https://sigsum.example.com/", "", "not-hex"); err == nil {
+		t.Error("newSigsumLogClient with an invalid key succeeded, want error")
+	}
+}
+
+func TestSigsumLeafValues(t *testing.T) {
+	client := &sigsumLogClient{}
+	if _, err := client.LeafValues(make([]byte, sigsumLeafSize-1)); err == nil {
+		t.Error("LeafValues of a short leaf succeeded, want error")
+	}
+	if got := client.FormatLeaf([]byte("short")); !strings.HasPrefix(got, "Failed to parse leaf") {
+		t.Errorf("FormatLeaf of a short leaf = %q", got)
+	}
+}
@@ -262,13 +262,19 @@ func TestStaticCTLogClient(t *testing.T) {
 	}
 
 	// Test GetLeaf
-	leafBytesRet, err := client.GetLeaf(cp, 0)
+	leaf, err := client.GetLeaf(cp, 0)
 	if err != nil {
 		t.Fatalf("failed to get leaf: %v", err)
 	}
+	if !leaf.Verified || leaf.Proof == nil || leaf.Proof.TreeSize != cp.Size {
+		t.Errorf("expected leaf to be verified against tree size %d, got %+v", cp.Size, leaf.Proof)
+	}
+	if want := rootHash[:]; string(leaf.LeafHash) != string(want) {
+		t.Errorf("expected leaf hash %x, got %x", want, leaf.LeafHash)
+	}
 
 	// Leaf should be formatted correctly
-	formatted := client.FormatLeaf(leafBytesRet)
+	formatted := client.FormatLeaf(leaf.Contents)
 	if !strings.Contains(formatted, "Subject: CN=woodpecker.test") {
 		t.Errorf("expected Subject in formatted leaf, got:\n%s", formatted)
 	}
@@ -282,6 +288,21 @@ func TestStaticCTLogClient(t *testing.T) {
 	if _, err := client.GetLeaf(cp, cp.Size); err == nil {
 		t.Errorf("expected error for index %d >= size %d, got nil", cp.Size, cp.Size)
 	}
+
+	// A leaf which isn't in the checkpoint's tree is returned unverified
+	// along with the error.
+	tileBytes = sunlight.AppendTileLeaf(nil, &sunlight.LogEntry{Certificate: certBytes, Timestamp: int64(timestamp) + 1})
+	client, err = newStaticCTLogClient(ts.URL, logName, pubKeyB64)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	leaf, err = client.GetLeaf(cp, 0)
+	if err == nil {
+		t.Fatal("expected error for a leaf not in the tree, got nil")
+	}
+	if leaf == nil || leaf.Verified || !strings.Contains(client.FormatLeaf(leaf.Contents), "Subject: CN=woodpecker.test") {
+		t.Errorf("expected the unverified leaf to be returned, got %+v", leaf)
+	}
 }
 
 func TestNewStaticCTLogClientEmptyOriginRawKey(t *testing.T) {
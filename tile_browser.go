@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tileBrowser is a hash tile, or the leaves under a level 0 tile, shown in
+// the tile browser.
+type tileBrowser struct {
+	level, index, width uint64
+	path                string
+	hashes              [][]byte
+	// bundle is set when showing the leaves under a level 0 tile, which
+	// are in leaves, with their leaf hashes in hashes. paths holds the
+	// files they were read from.
+	bundle bool
+	leaves [][]byte
+	paths  []string
+	cursor int
+}
+
+// loadTile fetches the hash tile at level and index in the tree of the
+// given size, or if bundle is set, the leaves under the level 0 tile at
+// index.
+func loadTile(ctx context.Context, client logClient, size, level, index uint64, bundle bool) (*tileBrowser, error) {
+	width, err := tileWidth(size, level, index)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := layoutFor(client.GetLogType())
+	if err != nil {
+		return nil, err
+	}
+	root, err := url.Parse(client.GetURL())
+	if err != nil {
+		return nil, err
+	}
+	fetch, err := newLogFetcher(client.GetOrigin(), root)
+	if err != nil {
+		return nil, err
+	}
+	t := &tileBrowser{level: level, index: index, width: width, path: layout.tilePath(level, index, width)}
+	if bundle && level == 0 {
+		t.bundle = true
+		t.paths = layout.leafPaths(index, width)
+		for _, p := range t.paths {
+			b, err := fetch(ctx, p)
+			if err != nil {
+				return nil, fmt.Errorf("fetching %s: %w", p, err)
+			}
+			leaves, hashes, err := layout.leaves(b)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p, err)
+			}
+			t.leaves = append(t.leaves, leaves...)
+			t.hashes = append(t.hashes, hashes...)
+		}
+		return t, nil
+	}
+	b, err := fetch(ctx, t.path)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", t.path, err)
+	}
+	if t.hashes, err = layout.tileHashes(b, width); err != nil {
+		return nil, fmt.Errorf("%s: %w", t.path, err)
+	}
+	return t, nil
+}
+
+// leafRange returns the range of leaves under the tile, [start, end).
+func (t *tileBrowser) leafRange(size uint64) (uint64, uint64) {
+	span := uint64(1) << (8 * (t.level + 1))
+	return t.index * span, min((t.index+1)*span, size)
+}
+
+// header returns the lines describing the tile above its rows.
+func (t *tileBrowser) header(size uint64) string {
+	kind := fmt.Sprintf("full, %d hashes", t.width)
+	if t.width < 256 {
+		kind = fmt.Sprintf("partial, %d of 256 hashes", t.width)
+	}
+	start, end := t.leafRange(size)
+	lines := []string{fmt.Sprintf("Level %d  •  Tile %d  •  %s  •  Leaves %d–%d", t.level, t.index, kind, start, end-1)}
+	if t.bundle {
+		files := strings.Join(t.paths, ", ")
+		if len(t.paths) > 1 {
+			files = fmt.Sprintf("%s … %s (%d files)", t.paths[0], t.paths[len(t.paths)-1], len(t.paths))
+		}
+		lines = append(lines, fmt.Sprintf("Leaves: %s", files))
+	} else {
+		lines = append(lines, fmt.Sprintf("Path: %s  •  Nodes at tree level %d", t.path, 8*t.level))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// render draws the rows of the tile, highlighting the row under the
+// cursor: each hash and the node it is, or each leaf with its hash.
+func (t *tileBrowser) render(client logClient, width int) string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+	var sb strings.Builder
+	if t.bundle {
+		sb.WriteString(dim.Render(fmt.Sprintf(" %-4s %-12s %-18s %s", "Pos", "Leaf", "Leaf hash", "Contents")))
+	} else {
+		sb.WriteString(dim.Render(fmt.Sprintf(" %-4s %-12s %s", "Pos", "Node", "Hash")))
+	}
+	for i, h := range t.hashes {
+		var line string
+		if t.bundle {
+			summary, _, _ := strings.Cut(strings.TrimSpace(client.FormatLeaf(t.leaves[i])), "\n")
+			line = fmt.Sprintf(" %-4d %-12d %-18s %s", i, t.index*256+uint64(i), fmt.Sprintf("%.8x…", h), summary)
+		} else {
+			line = fmt.Sprintf(" %-4d %-12d %x", i, t.index*256+uint64(i), h)
+		}
+		line = limitText(line, width, 1)
+		if i == t.cursor {
+			line = jsonCursorStyle.Render(line)
+		}
+		sb.WriteString("\n" + line)
+	}
+	return sb.String()
+}
+
+// tileBrowserMsg carries a tile loaded for the tile browser.
+type tileBrowserMsg struct {
+	origin string
+	tile   *tileBrowser
+	err    error
+}
+
+// loadTileCmd loads a tile into the tile browser, with the cursor on the
+// given row.
+func (m *Model) loadTileCmd(level, index uint64, bundle bool, cursor int) tea.Cmd {
+	client := m.currentClient
+	checkpoint := m.checkpoint
+	m.loadingTile = true
+	return func() tea.Msg {
+		msg := tileBrowserMsg{origin: client.GetOrigin()}
+		if checkpoint == nil {
+			msg.err = fmt.Errorf("no checkpoint loaded")
+			return msg
+		}
+		msg.tile, msg.err = loadTile(context.Background(), client, checkpoint.Size, level, index, bundle)
+		if msg.tile != nil {
+			msg.tile.cursor = min(cursor, len(msg.tile.hashes)-1)
+		}
+		return msg
+	}
+}
+
+// renderTileBrowser sets the tile browser content, scrolling to keep the
+// cursor in view.
+func (m *Model) renderTileBrowser() {
+	if m.tileBrowser == nil {
+		m.tileBrowserView.SetContent("")
+		return
+	}
+	m.tileBrowserView.SetContent(m.tileBrowser.render(m.currentClient, m.tileBrowserView.Width))
+	// The first line is the table header.
+	row := m.tileBrowser.cursor + 1
+	if row-1 < m.tileBrowserView.YOffset {
+		m.tileBrowserView.SetYOffset(row - 1)
+	} else if row >= m.tileBrowserView.YOffset+m.tileBrowserView.Height {
+		m.tileBrowserView.SetYOffset(row - m.tileBrowserView.Height + 1)
+	}
+}
+
+// updateTileBrowser handles a key in the tile browser.
+func (m *Model) updateTileBrowser(keyMsg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.tileGoto {
+		switch keyMsg.String() {
+		case "enter":
+			m.tileGoto = false
+			level, index, err := parseTileInput(m.tileInput.Value())
+			if err != nil {
+				m.tileBrowserErr = err
+				return m, nil
+			}
+			return m, m.loadTileCmd(level, index, false, 0)
+		case "esc":
+			m.tileGoto = false
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.tileInput, cmd = m.tileInput.Update(keyMsg)
+		return m, cmd
+	}
+	t := m.tileBrowser
+	switch keyMsg.String() {
+	case "q":
+		return m, tea.Quit
+	case "esc", "t":
+		m.activeView = "leaf"
+		return m, nil
+	case "g":
+		m.tileGoto = true
+		m.tileInput.Reset()
+		m.tileInput.Focus()
+		return m, nil
+	}
+	if t == nil || m.loadingTile {
+		return m, nil
+	}
+	switch keyMsg.String() {
+	case "up", "k":
+		if t.cursor > 0 {
+			t.cursor--
+		}
+		m.renderTileBrowser()
+	case "down", "j":
+		if t.cursor < len(t.hashes)-1 {
+			t.cursor++
+		}
+		m.renderTileBrowser()
+	case "left":
+		if t.index > 0 {
+			return m, m.loadTileCmd(t.level, t.index-1, t.bundle, t.cursor)
+		}
+	case "right":
+		return m, m.loadTileCmd(t.level, t.index+1, t.bundle, t.cursor)
+	case "enter":
+		switch {
+		case t.bundle:
+			// Open the leaf under the cursor.
+			m.activeView = "leaf"
+			m.loadingLeaf = true
+			return m, m.fetchLeafCmd(t.index*256 + uint64(t.cursor))
+		case t.level == 0:
+			return m, m.loadTileCmd(0, t.index, true, t.cursor)
+		default:
+			// Each hash is the root of a full tile on the level below.
+			return m, m.loadTileCmd(t.level-1, t.index*256+uint64(t.cursor), false, 0)
+		}
+	case "backspace", "u":
+		if t.bundle {
+			return m, m.loadTileCmd(0, t.index, false, t.cursor)
+		}
+		if m.checkpoint != nil {
+			if _, err := tileWidth(m.checkpoint.Size, t.level+1, t.index/256); err == nil {
+				return m, m.loadTileCmd(t.level+1, t.index/256, false, int(t.index%256))
+			}
+		}
+		m.tileBrowserErr = fmt.Errorf("level %d tile %d is the top of the tree", t.level, t.index)
+	case "V":
+		m.activeView = "verify"
+		m.tileInput.SetValue(fmt.Sprintf("%d %d", t.level, t.index))
+		m.tileInput.CursorEnd()
+		m.tileInput.Focus()
+		m.verifyingTile = true
+		m.tileResult, m.tileErr = nil, nil
+		return m, m.verifyTileCmd(m.tileInput.Value())
+	}
+	return m, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTileLayouts(t *testing.T) {
+	for _, tc := range []struct {
+		logType               string
+		partial, full, leaves string
+		leafFiles             int
+	}{
+		{logType: "tiles", partial: "tile/0/x001/234.p/5", full: "tile/1/003", leaves: "tile/entries/x001/234.p/5", leafFiles: 1},
+		{logType: "static-ct", partial: "tile/0/x001/234.p/5", full: "tile/1/003", leaves: "tile/data/x001/234.p/5", leafFiles: 1},
+		{logType: "sumdb", partial: "tile/8/0/x001/234.p/5", full: "tile/8/1/003", leaves: "tile/8/data/x001/234.p/5", leafFiles: 1},
+		{logType: "serverless", partial: "tile/00/0000/00/04/d2.05", full: "tile/01/0000/00/00/03", leaves: "seq/00/00/04/d2/00", leafFiles: 5},
+	} {
+		l, err := layoutFor(tc.logType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves := l.leafPaths(1234, 5)
+		if got := l.tilePath(0, 1234, 5); got != tc.partial {
+			t.Errorf("%s: partial tile path = %q, want %q", tc.logType, got, tc.partial)
+		}
+		if got := l.tilePath(1, 3, 256); got != tc.full {
+			t.Errorf("%s: full tile path = %q, want %q", tc.logType, got, tc.full)
+		}
+		if leaves[0] != tc.leaves || len(leaves) != tc.leafFiles {
+			t.Errorf("%s: leaf paths = %q, want %d starting with %q", tc.logType, leaves, tc.leafFiles, tc.leaves)
+		}
+	}
+}
+
+func TestTileWidth(t *testing.T) {
+	const size = 256*256 + 300
+	for _, tc := range []struct {
+		level, index, want uint64
+	}{
+		{0, 0, 256}, {0, 257, 44}, {1, 0, 256}, {1, 1, 1}, {2, 0, 1},
+	} {
+		if got, err := tileWidth(size, tc.level, tc.index); err != nil || got != tc.want {
+			t.Errorf("tileWidth(%d, %d) = %d, %v; want %d", tc.level, tc.index, got, err, tc.want)
+		}
+	}
+	if _, err := tileWidth(512, 0, 2); err == nil {
+		t.Error("tileWidth of a tile beyond a tree of full tiles succeeded")
+	}
+}
+
+func TestTileBrowser(t *testing.T) {
+	const origin = "example.com/browsed"
+	for _, tc := range []struct {
+		logType string
+		suffix  string
+		open    func(lr, origin, vkey string) (logClient, error)
+	}{
+		{logType: "tiles", open: newTLogTilesLogClient},
+		{logType: "sumdb", suffix: "\n", open: newSumDBLogClient},
+	} {
+		t.Run(tc.logType, func(t *testing.T) {
+			entries := testEntries(256*256+300, tc.suffix)
+			files, vkey := newTestLog(t, tc.logType, origin, entries)
+			client, err := tc.open(newTestLogServer(t, files).URL, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := NewModel([]string{origin}, map[string]logClient{origin: client}, nil, nil, origin, modelOptions{})
+			m.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+			processCmds(t, m, m.fetchCheckpointCmd())
+
+			// The browser opens on the tile of the current leaf, the last.
+			sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+			if m.activeView != "tiles" || m.tileBrowser == nil {
+				t.Fatalf("activeView = %q with tile %v, %v; want tiles", m.activeView, m.tileBrowser, m.tileBrowserErr)
+			}
+			if b := m.tileBrowser; b.level != 0 || b.index != 257 || b.width != 44 || b.cursor != 43 {
+				t.Errorf("opened tile level %d index %d width %d at row %d, want level 0 index 257 width 44 at row 43", b.level, b.index, b.width, b.cursor)
+			}
+			if !strings.Contains(m.View(), "partial, 44 of 256 hashes") {
+				t.Error("view doesn't describe the partial tile")
+			}
+
+			// Up to level 1, whose only full tile is 0, and to level 2.
+			sendString(t, m, "u")
+			if b := m.tileBrowser; b.level != 1 || b.index != 1 || b.width != 1 || b.cursor != 0 {
+				t.Errorf("parent is level %d index %d width %d, want level 1 index 1 width 1", b.level, b.index, b.width)
+			}
+			sendKey(t, m, tea.KeyMsg{Type: tea.KeyLeft})
+			if b := m.tileBrowser; b.level != 1 || b.index != 0 || b.width != 256 {
+				t.Errorf("previous tile is level %d index %d width %d, want level 1 index 0 width 256", b.level, b.index, b.width)
+			}
+
+			// Down to level 0 tile 2, and its leaves.
+			sendString(t, m, "jj")
+			sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+			if b := m.tileBrowser; b.level != 0 || b.index != 2 || b.width != 256 || b.bundle {
+				t.Fatalf("child is level %d index %d width %d, want level 0 index 2 width 256", b.level, b.index, b.width)
+			}
+			sendString(t, m, "j")
+			sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+			if b := m.tileBrowser; !b.bundle || len(b.leaves) != 256 || string(b.leaves[1]) != entries[513] {
+				t.Fatalf("leaves of tile 2 = %d leaves, bundle %v", len(b.leaves), b.bundle)
+			}
+			if !strings.Contains(m.View(), strings.TrimSpace(entries[513])) {
+				t.Error("view doesn't show the leaves")
+			}
+
+			// Enter on a leaf opens it.
+			sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+			if m.activeView != "leaf" || m.leaf.Index != 513 || !m.leaf.Verified {
+				t.Errorf("activeView = %q at leaf %d (verified %v), want leaf 513", m.activeView, m.leaf.Index, m.leaf.Verified)
+			}
+
+			// Go to a tile.
+			sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+			sendString(t, m, "g2 0")
+			sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+			if b := m.tileBrowser; b.level != 2 || b.index != 0 || b.width != 1 {
+				t.Errorf("went to level %d index %d width %d, want level 2 index 0 width 1", b.level, b.index, b.width)
+			}
+			sendString(t, m, "u")
+			if m.tileBrowserErr == nil {
+				t.Error("going up from the top of the tree succeeded")
+			}
+			sendString(t, m, "g0 300")
+			sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+			if m.tileBrowserErr == nil || m.tileBrowser.level != 2 {
+				t.Errorf("going to a tile outside the tree = %v, want an error and the tile kept", m.tileBrowserErr)
+			}
+			sendKey(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+			if m.activeView != "leaf" {
+				t.Errorf("activeView = %q, want leaf", m.activeView)
+			}
+		})
+	}
+}
+
+func TestTileBrowserVerify(t *testing.T) {
+	const origin = "example.com/browsed"
+	files, vkey := newTestLog(t, "tiles", origin, testEntries(600, ""))
+	client, err := newTLogTilesLogClient(newTestLogServer(t, files).URL, origin, vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel([]string{origin}, map[string]logClient{origin: client}, nil, nil, origin, modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	processCmds(t, m, m.fetchCheckpointCmd())
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyLeft})
+	sendString(t, m, "V")
+	if m.activeView != "verify" || m.tileResult == nil || m.tileResult.index != 1 || m.tileResult.mismatch != nil {
+		t.Errorf("verifying tile 0/1 from the browser = %v, %v in view %q", m.tileResult, m.tileErr, m.activeView)
+	}
+	if got, want := m.tileInput.Value(), fmt.Sprintf("%d %d", 0, 1); got != want {
+		t.Errorf("tile input = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/sync/errgroup"
+)
+
+// tileVerification is the result of checking a published hash tile against
+// the data it's computed from.
+type tileVerification struct {
+	level, index, width uint64
+	path                string
+	// sources is how many files the tile was recomputed from: entry bundles
+	// or leaf files for level 0 tiles, and tiles from the level below
+	// otherwise.
+	sources  int
+	mismatch *tileMismatch
+}
+
+// verifyTile checks the hash tile at level and index in the tree of the
+// given size. A level 0 tile is checked against the hashes of the leaves in
+// its entry bundles, and a higher tile against the roots of the full tiles
+// below it. Inclusion proofs only use the tiles, so this catches logs that
+// serve tiles and entry bundles which don't agree.
+func verifyTile(ctx context.Context, client logClient, size, level, index uint64) (*tileVerification, error) {
+	width, err := tileWidth(size, level, index)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := layoutFor(client.GetLogType())
+	if err != nil {
+		return nil, err
+	}
+	root, err := url.Parse(client.GetURL())
+	if err != nil {
+		return nil, err
+	}
+	fetch, err := newLogFetcher(client.GetOrigin(), root)
+	if err != nil {
+		return nil, err
+	}
+	a := &auditor{fetch: fetch, layout: layout, size: size}
+	v := &tileVerification{level: level, index: index, width: width, path: layout.tilePath(level, index, width)}
+	if level == 0 {
+		t, err := a.fetchTile(ctx, index)
+		if err != nil {
+			return nil, err
+		}
+		a.check(0, index, t.hashes, t.published)
+		v.sources = len(layout.leafPaths(index, width))
+	} else {
+		want := make([][]byte, width)
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(mirrorConcurrency)
+		for i := range want {
+			g.Go(func() error {
+				p := layout.tilePath(level-1, index*256+uint64(i), 256)
+				b, err := fetch(gctx, p)
+				if err != nil {
+					return fmt.Errorf("fetching %s: %w", p, err)
+				}
+				hashes, err := layout.tileHashes(b, 256)
+				if err != nil {
+					return fmt.Errorf("%s: %w", p, err)
+				}
+				want[i] = subtreeRoot(hashes)
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+		if err := a.fetchAndCheck(ctx, level, index, want); err != nil {
+			return nil, err
+		}
+		v.sources = int(width)
+	}
+	if len(a.report.mismatches) > 0 {
+		v.mismatch = &a.report.mismatches[0]
+	}
+	return v, nil
+}
+
+// render returns the verification result as lines of text.
+func (v *tileVerification) render() string {
+	good := lipgloss.NewStyle().Foreground(lipgloss.Color("#34D399"))
+	bad := lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171"))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Tile level %d index %d width %d (%s)\n", v.level, v.index, v.width, v.path)
+	if v.level == 0 {
+		fmt.Fprintf(&sb, "Recomputed from the leaf hashes of %d leaf file(s)\n\n", v.sources)
+	} else {
+		fmt.Fprintf(&sb, "Recomputed from the roots of %d tile(s) at level %d\n\n", v.sources, v.level-1)
+	}
+	switch {
+	case v.mismatch == nil:
+		sb.WriteString(good.Render(fmt.Sprintf("✓ All %d hashes match", v.width)))
+	case v.mismatch.err != nil:
+		sb.WriteString(bad.Render(fmt.Sprintf("✗ Invalid tile: %v", v.mismatch.err)))
+	default:
+		m := v.mismatch
+		sb.WriteString(bad.Render(fmt.Sprintf("✗ %d of %d hashes differ", m.differing, v.width)))
+		fmt.Fprintf(&sb, "\nFirst at position %d (node %d at tree level %d)\n  published:  %x\n  recomputed: %x", m.position, m.index*256+m.position, 8*m.level, m.got, m.want)
+	}
+	return sb.String()
+}
+
+// parseTileInput parses the TUI's tile verification input, "LEVEL INDEX".
+func parseTileInput(input string) (level, index uint64, err error) {
+	f := strings.Fields(input)
+	if len(f) != 2 {
+		return 0, 0, fmt.Errorf("want LEVEL INDEX, got %q", input)
+	}
+	if level, err = strconv.ParseUint(f[0], 10, 64); err != nil || level > 7 {
+		return 0, 0, fmt.Errorf("invalid level %q", f[0])
+	}
+	if index, err = strconv.ParseUint(f[1], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid index %q", f[1])
+	}
+	return level, index, nil
+}
+
+// tileVerifiedMsg reports the result of verifying a tile from the TUI.
+type tileVerifiedMsg struct {
+	origin string
+	result *tileVerification
+	err    error
+}
+
+// verifyTileCmd verifies the tile given by the tile input in the tree of
+// the current checkpoint.
+func (m *Model) verifyTileCmd(input string) tea.Cmd {
+	client := m.currentClient
+	checkpoint := m.checkpoint
+	return func() tea.Msg {
+		msg := tileVerifiedMsg{origin: client.GetOrigin()}
+		if checkpoint == nil {
+			msg.err = fmt.Errorf("no checkpoint loaded")
+			return msg
+		}
+		level, index, err := parseTileInput(input)
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		msg.result, msg.err = verifyTile(context.Background(), client, checkpoint.Size, level, index)
+		return msg
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestVerifyTile(t *testing.T) {
+	const origin = "example.com/tiles"
+	entries := testEntries(256*256+300, "")
+	layout, _ := layoutFor("tiles")
+	files, vkey := newTestLog(t, "tiles", origin, entries)
+	// The level 0 tile 4 and its entry bundle don't agree, but the tiles
+	// are consistent with each other, so inclusion proofs still verify.
+	p := layout.leafPaths(4, 256)[0]
+	files[p] = bytes.Replace(files[p], []byte("entry 1030"), []byte("entry X030"), 1)
+	client, err := newTLogTilesLogClient(newTestLogServer(t, files).URL, origin, vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := uint64(len(entries))
+
+	for _, tc := range []struct {
+		level, index, width uint64
+		differing           int
+		position            uint64
+	}{
+		{level: 0, index: 0, width: 256},
+		{level: 0, index: 4, width: 256, differing: 1, position: 6},
+		{level: 0, index: 257, width: 44},
+		{level: 1, index: 0, width: 256},
+		{level: 1, index: 1, width: 1},
+		{level: 2, index: 0, width: 1},
+	} {
+		t.Run(fmt.Sprintf("level %d index %d", tc.level, tc.index), func(t *testing.T) {
+			v, err := verifyTile(t.Context(), client, size, tc.level, tc.index)
+			if err != nil {
+				t.Fatalf("verifyTile: %v", err)
+			}
+			if v.width != tc.width || v.path != layout.tilePath(tc.level, tc.index, tc.width) {
+				t.Errorf("verified %s with width %d, want width %d", v.path, v.width, tc.width)
+			}
+			switch {
+			case tc.differing == 0 && v.mismatch != nil:
+				t.Errorf("verifyTile found %v, want a match", v.mismatch)
+			case tc.differing > 0 && (v.mismatch == nil || v.mismatch.differing != tc.differing || v.mismatch.position != tc.position):
+				t.Errorf("verifyTile found %v, want %d differing from position %d", v.mismatch, tc.differing, tc.position)
+			}
+		})
+	}
+
+	for _, tc := range []struct{ level, index uint64 }{{0, 258}, {1, 2}, {2, 1}, {3, 0}} {
+		if _, err := verifyTile(t.Context(), client, size, tc.level, tc.index); err == nil {
+			t.Errorf("verifyTile(%d, %d) of a tile outside the tree succeeded", tc.level, tc.index)
+		}
+	}
+}
+
+func TestVerifyTileView(t *testing.T) {
+	const origin = "example.com/tui-tiles"
+	entries := testEntries(600, "")
+	layout, _ := layoutFor("tiles")
+	files, vkey := newTestLog(t, "tiles", origin, entries)
+	files[layout.tilePath(0, 1, 256)][0] ^= 1
+	client, err := newTLogTilesLogClient(newTestLogServer(t, files).URL, origin, vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel([]string{origin}, map[string]logClient{origin: client}, nil, nil, origin, modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	processCmds(t, m, m.fetchCheckpointCmd())
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'V'}})
+	if m.activeView != "verify" {
+		t.Fatalf("activeView = %q, want verify", m.activeView)
+	}
+	// The input starts with the level 0 tile of the current leaf, 599.
+	if got := m.tileInput.Value(); got != "0 2" {
+		t.Errorf("tile input = %q, want %q", got, "0 2")
+	}
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.tileErr != nil || m.tileResult == nil || m.tileResult.mismatch != nil {
+		t.Errorf("verifying tile 0/2 = %v, %v; want a match", m.tileResult, m.tileErr)
+	}
+	if !strings.Contains(m.View(), "All 88 hashes match") {
+		t.Error("view doesn't show the tile matched")
+	}
+
+	m.tileInput.SetValue("0 1")
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.tileResult == nil || m.tileResult.mismatch == nil {
+		t.Fatalf("verifying tampered tile 0/1 = %v, %v; want a mismatch", m.tileResult, m.tileErr)
+	}
+	if !strings.Contains(m.View(), "1 of 256 hashes differ") {
+		t.Error("view doesn't show the tile's mismatch")
+	}
+
+	m.tileInput.SetValue("zero 1")
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.tileErr == nil {
+		t.Error("verifying an invalid tile succeeded")
+	}
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.activeView != "leaf" {
+		t.Errorf("activeView = %q, want leaf", m.activeView)
+	}
+}
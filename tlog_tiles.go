@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mhutchinson/woodpecker/model"
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/rfc6962"
+	serverless_client "github.com/transparency-dev/serverless-log/client"
+	"golang.org/x/mod/sumdb/note"
+	"golang.org/x/mod/sumdb/tlog"
+	"k8s.io/klog/v2"
+)
+
+// tilesLayout is the layout of c2sp.org/tlog-tiles logs.
+var tilesLayout, _ = layoutFor("tiles")
+
+func newTLogTilesLogClient(lr string, origin string, vkey string) (logClient, error) {
+	if !strings.HasSuffix(lr, "/") {
+		lr = lr + "/"
+	}
+	logRoot, err := url.Parse(lr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL %q: %w", lr, err)
+	}
+	verifier, err := note.NewVerifier(vkey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verifier: %w", err)
+	}
+	if len(origin) == 0 {
+		origin = verifier.Name()
+		klog.Infof("No origin provided; using verifier name: %q", origin)
+	}
+	fetcher, err := newLogFetcher(origin, logRoot)
+	if err != nil {
+		return nil, err
+	}
+	return &tLogTilesLogClient{
+		url:      lr,
+		origin:   origin,
+		verifier: verifier,
+		fetcher:  fetcher,
+		tiles:    tileReader{layout: tilesLayout, fetch: fetcher},
+	}, nil
+}
+
+// tLogTilesLogClient reads logs that follow c2sp.org/tlog-tiles.
+type tLogTilesLogClient struct {
+	url      string
+	origin   string
+	verifier note.Verifier
+	fetcher  serverless_client.Fetcher
+	tiles    tileReader
+	bundles  bundleCache
+}
+
+func (c *tLogTilesLogClient) GetLogType() string {
+	return "tiles"
+}
+
+func (c *tLogTilesLogClient) GetURL() string {
+	return c.url
+}
+
+func (c *tLogTilesLogClient) GetOrigin() string {
+	return c.origin
+}
+
+func (c *tLogTilesLogClient) GetVerifier() note.Verifier {
+	return c.verifier
+}
+
+func (c *tLogTilesLogClient) GetCheckpoint() (*model.Checkpoint, error) {
+	cpRaw, err := c.fetcher(context.Background(), tilesLayout.checkpointPath)
+	if err != nil {
+		return nil, err
+	}
+	cp, _, n, err := log.ParseCheckpoint(cpRaw, c.origin, c.verifier)
+	return &model.Checkpoint{
+		Checkpoint: cp,
+		Raw:        cpRaw,
+		Note:       n,
+	}, err
+}
+
+func (c *tLogTilesLogClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error) {
+	if checkpoint == nil {
+		return nil, errors.New("checkpoint is nil")
+	}
+	if index >= checkpoint.Size {
+		return nil, fmt.Errorf("index %d out of bounds for checkpoint size %d", index, checkpoint.Size)
+	}
+	// The bundle is the one for this checkpoint, which is partial if it is
+	// on the right edge of the tree.
+	n, width := index/256, min(256, checkpoint.Size-index/256*256)
+	entries, err := c.bundles.get(n, width, func() ([][]byte, error) {
+		p := tilesLayout.leafPaths(n, width)[0]
+		b, full, err := c.tiles.fetchPartial(context.Background(), p, tilesLayout.leafPaths(n, 256)[0], width)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := parseEntryBundle(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		if full && uint64(len(entries)) > width {
+			entries = entries[:width]
+		}
+		if uint64(len(entries)) != width {
+			return nil, fmt.Errorf("entry bundle truncated: %s has %d entries, want %d", p, len(entries), width)
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	leaf := entries[index%256]
+
+	p, err := c.inclusionProof(checkpoint, index, leaf)
+	if err != nil {
+		return &model.Leaf{Contents: leaf, Index: index, LeafHash: rfc6962.DefaultHasher.HashLeaf(leaf)}, err
+	}
+	return verifyLeaf(checkpoint, leaf, p)
+}
+
+// parseEntryBundle splits an entry bundle into its entries, each of which
+// is prefixed by its length as a big-endian uint16.
+func parseEntryBundle(b []byte) ([][]byte, error) {
+	var entries [][]byte
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return nil, fmt.Errorf("entry %d: truncated length", len(entries))
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+n {
+			return nil, fmt.Errorf("entry %d: has %d bytes, want %d", len(entries), len(b)-2, n)
+		}
+		entries = append(entries, b[2:2+n])
+		b = b[2+n:]
+	}
+	return entries, nil
+}
+
+func (c *tLogTilesLogClient) inclusionProof(checkpoint *model.Checkpoint, index uint64, leaf []byte) (*model.InclusionProof, error) {
+	var th tlog.Hash
+	copy(th[:], checkpoint.Hash)
+	tree := tlog.Tree{N: int64(checkpoint.Size), Hash: th}
+	rp, err := tlog.ProveRecord(tree.N, int64(index), tlog.TileHashReader(tree, c.tiles))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build inclusion proof: %w", err)
+	}
+	return &model.InclusionProof{
+		Index:    index,
+		TreeSize: checkpoint.Size,
+		LeafHash: rfc6962.DefaultHasher.HashLeaf(leaf),
+		Hashes:   recordProofHashes(rp),
+	}, nil
+}
+
+func (c *tLogTilesLogClient) FormatLeaf(leaf []byte) string {
+	return string(leaf)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTLogTilesConformance(t *testing.T) {
+	const origin = "example.com/conformance"
+	for _, size := range []int{1, 2, 255, 256, 257, 511, 512, 513, 256*256 - 1, 256 * 256, 256*256 + 1} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			entries := testEntries(size, "")
+			// An empty entry is valid.
+			entries[size/2] = ""
+			files, vkey := newTestLog(t, "tiles", origin, entries)
+			client, err := newTLogTilesLogClient(newTestLogServer(t, files).URL, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cp, err := client.GetCheckpoint()
+			if err != nil {
+				t.Fatalf("GetCheckpoint: %v", err)
+			}
+			if cp.Size != uint64(size) {
+				t.Fatalf("checkpoint size = %d, want %d", cp.Size, size)
+			}
+			for _, i := range []int{0, size / 2, size - 1, 255, 256, 257, 511, 512} {
+				if i >= size {
+					continue
+				}
+				l, err := client.GetLeaf(cp, uint64(i))
+				if err != nil {
+					t.Fatalf("GetLeaf(%d): %v", i, err)
+				}
+				if string(l.Contents) != entries[i] || !l.Verified {
+					t.Errorf("GetLeaf(%d) = %q (verified %v), want verified %q", i, l.Contents, l.Verified, entries[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTLogTilesPartialFallback(t *testing.T) {
+	// A log of 300 entries which has since grown to 512, and deleted its
+	// partial level 0 tile and bundle now that the full ones exist.
+	const origin = "example.com/grown"
+	entries := testEntries(512, "")
+	files, vkey := newTestLog(t, "tiles", origin, entries[:300])
+	grown, _ := newTestLog(t, "tiles", origin, entries)
+	for p := range files {
+		if strings.HasSuffix(p, ".p/44") {
+			delete(files, p)
+		}
+	}
+	for p, b := range grown {
+		if _, ok := files[p]; !ok && p != "checkpoint" {
+			files[p] = b
+		}
+	}
+	srv := newTestLogServer(t, files)
+	client, err := newTLogTilesLogClient(srv.URL, origin, vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, err := client.GetCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []uint64{0, 256, 299} {
+		l, err := client.GetLeaf(cp, i)
+		if err != nil {
+			t.Fatalf("GetLeaf(%d): %v", i, err)
+		}
+		if string(l.Contents) != entries[i] || !l.Verified || l.Proof.TreeSize != 300 {
+			t.Errorf("GetLeaf(%d) = %q (verified %v), want %q verified in size 300", i, l.Contents, l.Verified, entries[i])
+		}
+	}
+	if srv.requests["tile/entries/001.p/44"] != 1 || srv.requests["tile/entries/001"] != 1 {
+		t.Errorf("requests %v, want the partial bundle and then the full one", srv.requests)
+	}
+}
+
+func TestTLogTilesRejectsBadBundles(t *testing.T) {
+	const origin = "example.com/bad"
+	for _, tc := range []struct {
+		name   string
+		bundle func(b []byte) []byte
+		want   string
+	}{
+		{name: "truncated entry", bundle: func(b []byte) []byte { return b[:len(b)-1] }, want: "entry 4: has"},
+		{name: "truncated length", bundle: func(b []byte) []byte { return append(b, 0) }, want: "entry 5: truncated length"},
+		{name: "missing entry", bundle: func(b []byte) []byte { return b[:len(b)-2-len("entry 4")] }, want: "has 4 entries, want 5"},
+		{name: "changed entry", bundle: func(b []byte) []byte { return bytes.Replace(b, []byte("entry 1"), []byte("entry X"), 1) }, want: "inclusion proof"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			files, vkey := newTestLog(t, "tiles", origin, testEntries(5, ""))
+			files["tile/entries/000.p/5"] = tc.bundle(files["tile/entries/000.p/5"])
+			client, err := newTLogTilesLogClient(newTestLogServer(t, files).URL, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cp, err := client.GetCheckpoint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := client.GetLeaf(cp, 1)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("GetLeaf of a bad bundle = %v, %v; want error containing %q", l, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetLeafWithoutProof(t *testing.T) {
+	const origin = "example.com/unproven"
+	for _, tc := range []struct {
+		logType string
+		suffix  string
+		open    func(lr, origin, vkey string) (logClient, error)
+	}{
+		{logType: "tiles", open: newTLogTilesLogClient},
+		{logType: "sumdb", suffix: "\n", open: newSumDBLogClient},
+		{logType: "serverless", open: newServerlessLogClient},
+	} {
+		t.Run(tc.logType, func(t *testing.T) {
+			entries := testEntries(5, tc.suffix)
+			files, vkey := newTestLog(t, tc.logType, origin, entries)
+			// Without the hash tile the leaf can't be proven, but it is still
+			// returned, unverified.
+			layout, err := layoutFor(tc.logType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			delete(files, layout.tilePath(0, 0, 5))
+			client, err := tc.open(newTestLogServer(t, files).URL, origin, vkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cp, err := client.GetCheckpoint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := client.GetLeaf(cp, 1)
+			if err == nil {
+				t.Fatal("GetLeaf without a hash tile succeeded, want error")
+			}
+			if l == nil || l.Verified || string(l.Contents) != entries[1] || l.Index != 1 {
+				t.Errorf("GetLeaf without a hash tile = %+v, want leaf 1 %q unverified", l, entries[1])
+			}
+		})
+	}
+}
@@ -15,9 +15,8 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mhutchinson/woodpecker/model"
 	"github.com/sahilm/fuzzy"
-	distclient "github.com/transparency-dev/distributor/client"
-	"github.com/transparency-dev/formats/log"
 	"golang.org/x/mod/sumdb/note"
+	"k8s.io/klog/v2"
 )
 
 // logItem wraps log origins for use in bubbles/list.
@@ -34,54 +33,209 @@ func (i logItem) FilterValue() string {
 }
 
 // Messages for the Bubble Tea loop.
-type tickMsg struct{}
+// tickMsg triggers a checkpoint fetch. Ticks from a superseded schedule,
+// with an old gen, are ignored.
+type tickMsg struct {
+	gen int
+}
 
 type checkpointMsg struct {
+	origin     string
 	checkpoint *model.Checkpoint
 	witnessed  *model.Checkpoint
-	err        error
+	// policy is the witness policy evaluated against cosigned, the
+	// checkpoint returned by the distributor. If the policy was not
+	// satisfied then witnessed is nil.
+	policy   *policyResult
+	cosigned *note.Note
+	// distributors holds what each distributor returned, and splitView is
+	// true if any of them returned different checkpoints of the same size.
+	distributors []distributorResult
+	splitView    bool
+	err          error
 }
 
 type leafMsg struct {
 	leaf model.Leaf
-	err  error
+	// checkpoint is the checkpoint that the leaf was proven against.
+	checkpoint *model.Checkpoint
+	// witnessed is true if checkpoint is the witnessed checkpoint, and
+	// notWitnessed is true if the leaf is beyond the witnessed checkpoint.
+	witnessed    bool
+	notWitnessed bool
+	err          error
+}
+
+// witnessesMsg carries the witnesses to trust after fetching those
+// advertised by the distributors. unknown and missing name the advertised
+// witnesses that are not pinned, and the pinned witnesses that are not
+// advertised.
+type witnessesMsg struct {
+	verifiers []note.Verifier
+	unknown   []string
+	missing   []string
+	err       error
+}
+
+// witnessStatusMsg carries each witness's view of the log with the given
+// origin.
+type witnessStatusMsg struct {
+	origin   string
+	statuses []witnessStatus
 }
 
-type distributorClient interface {
-	GetCheckpointN(l distclient.LogID, n uint) ([]byte, error)
+// dashboardMsg carries the result of refreshing every log for the dashboard.
+type dashboardMsg struct {
+	results []dashboardResult
 }
 
+// retryWitnessesMsg triggers another attempt at fetching the witness keys.
+type retryWitnessesMsg struct{}
+
+// witnessRetryInterval is how long to wait before retrying the distributor
+// after failing to fetch the witness keys.
+const witnessRetryInterval = 30 * time.Second
+
 // Model represents the state of our Bubble Tea TUI.
 type Model struct {
-	logOrigins    []string
-	logClients    map[string]logClient
-	distributor   distributorClient
-	witVerifiers  []note.Verifier
-	currentLog    string
-	currentClient logClient
+	logOrigins []string
+	logClients map[string]logClient
+	// distributors is empty if witnessed checkpoints are disabled.
+	// witVerifiers are the witnesses trusted to cosign checkpoints. These
+	// are the pinnedWitnesses combined with those advertised by the
+	// distributors according to witnessMode, once witnessesFetched.
+	// witnessesErr holds the last error from fetching them.
+	distributors     []distributor
+	witVerifiers     []note.Verifier
+	pinnedWitnesses  []note.Verifier
+	witnessMode      string
+	witnessesFetched bool
+	witnessesErr     error
+	unknownWitnesses []string
+	missingWitnesses []string
+	currentLog       string
+	currentClient    logClient
 
 	// App state
 	checkpoint *model.Checkpoint
 	witnessed  *model.Checkpoint
 	witnessN   uint
-	leaf       model.Leaf
-	activeErr  error
+	// chosenWitnessN is the number of cosignatures chosen with w and W,
+	// which witnessN is for logs without a witness policy.
+	chosenWitnessN uint
+	// distResults and splitView are from the last query of the distributors.
+	distResults []distributorResult
+	splitView   bool
+	leaf        model.Leaf
+	activeErr   error
+
+	// witnessPolicies holds the witness policy for each log origin, with
+	// the default policy under the empty origin. policyResult is the
+	// evaluation of the current log's policy against policyNote.
+	witnessPolicies map[string]*witnessPolicy
+	// staleWitnessThreshold is the cosignature age beyond which a witness is
+	// shown as stale.
+	staleWitnessThreshold time.Duration
+	policyResult          *policyResult
+	policyNote            *note.Note
+
+	// witnessStatuses is each witness's latest view of the current log.
+	witnessStatuses      []witnessStatus
+	loadingWitnessStatus bool
+
+	// leafCheckpoint is the checkpoint that leaf was proven against, and
+	// leafErr is the error from fetching or verifying it.
+	leafCheckpoint *model.Checkpoint
+	leafErr        error
+
+	// proveWitnessed selects proving leaves against the witnessed checkpoint
+	// rather than the log's. leafWitnessed and leafNotWitnessed record the
+	// outcome of this for the current leaf.
+	proveWitnessed   bool
+	leafWitnessed    bool
+	leafNotWitnessed bool
+
+	// Leaf rendering state
+	renderer  string    // one of rendererAuto, rendererJSON, rendererText
+	jsonView  *jsonView // non-nil when the current leaf is shown as JSON
+	statusMsg string
 
 	// Sub-components
-	list      list.Model
-	textInput textinput.Model
-	spinner   spinner.Model
-	viewport  viewport.Model
+	list        list.Model
+	textInput   textinput.Model
+	spinner     spinner.Model
+	viewport    viewport.Model
+	proofView   viewport.Model
+	witnessView viewport.Model
+
+	// fetchStats is shown in the HTTP statistics overlay.
+	fetchStats *fetchStats
+
+	// Checkpoint polling state. refreshIntervals is keyed by origin, with
+	// the default for all logs under "". refreshFailures counts consecutive
+	// failed fetches, and tickGen identifies the pending tick.
+	refreshIntervals map[string]time.Duration
+	refreshFailures  int
+	nextRefresh      time.Time
+	tickGen          int
+
+	// Follow mode state. followNext is the index of the next leaf to
+	// stream, and followStart and followStartSize are when follow mode was
+	// turned on and the log size then.
+	followLeaves    []followLeaf
+	followNext      uint64
+	followStart     time.Time
+	followStartSize uint64
+	loadingFollow   bool
+
+	// Checkpoints observed for each log, keyed by origin.
+	history       map[string]*checkpointHistory
+	historyView   viewport.Model
+	historyCursor int
+
+	// Export state. exportInput holds "START END FORMAT OUT".
+	exportInput textinput.Model
+	exporting   bool
+
+	// Tile verification state. tileInput holds "LEVEL INDEX".
+	tileInput     textinput.Model
+	tileResult    *tileVerification
+	tileErr       error
+	verifyingTile bool
+
+	// Tile browser state. tileGoto is set while tileInput is used to pick
+	// the tile to show.
+	tileBrowser     *tileBrowser
+	tileBrowserView viewport.Model
+	tileBrowserErr  error
+	loadingTile     bool
+	tileGoto        bool
+
+	// Dashboard state, keyed by log origin.
+	dashboard        map[string]*logHealth
+	dashboardView    viewport.Model
+	dashboardCursor  int
+	loadingDashboard bool
 
 	// UI layout state
-	activeView   string // "leaf", "logs", "jump"
+	activeView   string // "leaf", "logs", "jump", "proof", "witnesses", "dashboard", "history", "follow", "stats", "export", "verify", "tiles"
 	width        int
 	height       int
 	loadingCheck bool
 	loadingLeaf  bool
 }
 
-func NewModel(origins []string, clients map[string]logClient, dist distributorClient, witVers []note.Verifier, initialLog string) *Model {
+// modelOptions holds the settings of a Model that come from flags. The zero
+// value of each field selects its default.
+type modelOptions struct {
+	pinnedWitnesses       []note.Verifier
+	witnessMode           string
+	witnessPolicies       map[string]*witnessPolicy
+	staleWitnessThreshold time.Duration
+	refreshIntervals      map[string]time.Duration
+}
+
+func NewModel(origins []string, clients map[string]logClient, dists []distributor, witVers []note.Verifier, initialLog string, opts modelOptions) *Model {
 	items := make([]list.Item, len(origins))
 	for i, o := range origins {
 		client, ok := clients[o]
@@ -108,6 +262,15 @@ func NewModel(origins []string, clients map[string]logClient, dist distributorCl
 	ti.CharLimit = 20
 	ti.Width = 20
 
+	ei := textinput.New()
+	ei.Placeholder = "START END FORMAT OUT"
+	ei.CharLimit = 256
+
+	vi := textinput.New()
+	vi.Placeholder = "LEVEL INDEX"
+	vi.CharLimit = 24
+	vi.Width = 24
+
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#14B8A6"))
@@ -115,37 +278,77 @@ func NewModel(origins []string, clients map[string]logClient, dist distributorCl
 	vp := viewport.New(0, 0)
 
 	m := &Model{
-		logOrigins:    origins,
-		logClients:    clients,
-		distributor:   dist,
-		witVerifiers:  witVers,
-		currentLog:    initialLog,
-		currentClient: clients[initialLog],
-		witnessN:      2,
-		list:          l,
-		textInput:     ti,
-		spinner:       s,
-		viewport:      vp,
-		activeView:    "leaf",
-		loadingCheck:  true,
-		loadingLeaf:   true,
+		logOrigins:     origins,
+		logClients:     clients,
+		distributors:   dists,
+		witVerifiers:   witVers,
+		currentLog:     initialLog,
+		currentClient:  clients[initialLog],
+		witnessN:       2,
+		chosenWitnessN: 2,
+		witnessMode:    witnessesUnion,
+
+		pinnedWitnesses:       opts.pinnedWitnesses,
+		witnessPolicies:       opts.witnessPolicies,
+		staleWitnessThreshold: defaultStaleWitnessThreshold,
+		refreshIntervals:      opts.refreshIntervals,
+		renderer:              rendererAuto,
+		list:                  l,
+		textInput:             ti,
+		exportInput:           ei,
+		tileInput:             vi,
+		spinner:               s,
+		viewport:              vp,
+		proofView:             viewport.New(0, 0),
+		witnessView:           viewport.New(0, 0),
+		fetchStats:            httpStats,
+		history:               make(map[string]*checkpointHistory),
+		historyView:           viewport.New(0, 0),
+		dashboard:             make(map[string]*logHealth),
+		dashboardView:         viewport.New(0, 0),
+		tileBrowserView:       viewport.New(0, 0),
+		activeView:            "leaf",
+		loadingCheck:          true,
+		loadingLeaf:           true,
 	}
+	if opts.witnessMode != "" {
+		m.witnessMode = opts.witnessMode
+	}
+	if opts.staleWitnessThreshold != 0 {
+		m.staleWitnessThreshold = opts.staleWitnessThreshold
+	}
+	m.selectLog(initialLog)
 
 	return m
 }
 
 func (m *Model) Init() tea.Cmd {
-	return tea.Batch(
+	cmds := []tea.Cmd{
 		m.spinner.Tick,
 		m.fetchCheckpointCmd(),
-		m.startPeriodicTicker(),
-	)
+	}
+	if len(m.distributors) > 0 && m.witnessMode != witnessesIgnore && !m.witnessesFetched {
+		cmds = append(cmds, m.fetchWitnessesCmd())
+	}
+	return tea.Batch(cmds...)
 }
 
-func (m *Model) startPeriodicTicker() tea.Cmd {
-	return tea.Tick(5*time.Second, func(t time.Time) tea.Msg {
-		return tickMsg{}
-	})
+// fetchWitnessesCmd fetches the keys of the witnesses known to the
+// distributors, and combines them with the pinned witnesses. This happens in
+// the background so that logs can be browsed while the distributors are
+// unreachable.
+func (m *Model) fetchWitnessesCmd() tea.Cmd {
+	distributors := m.distributors
+	pinned := m.pinnedWitnesses
+	mode := m.witnessMode
+	return func() tea.Msg {
+		advertised, err := fetchWitnessVerifiers(distributors)
+		if err != nil {
+			return witnessesMsg{err: fmt.Errorf("failed to fetch witnesses: %w", err)}
+		}
+		vs, unknown, missing := combineWitnesses(mode, pinned, advertised)
+		return witnessesMsg{verifiers: vs, unknown: unknown, missing: missing}
+	}
 }
 
 func (m *Model) selectLog(origin string) {
@@ -155,52 +358,195 @@ func (m *Model) selectLog(origin string) {
 		m.checkpoint = nil
 		m.witnessed = nil
 		m.leaf = model.Leaf{}
+		m.jsonView = nil
+		m.statusMsg = ""
+		m.leafCheckpoint = nil
+		m.leafWitnessed = false
+		m.leafNotWitnessed = false
+		m.leafErr = nil
 		m.activeErr = nil
+		m.policyResult = nil
+		m.policyNote = nil
+		m.distResults = nil
+		m.splitView = false
+		m.witnessStatuses = nil
+		m.loadingWitnessStatus = false
+		m.refreshFailures = 0
 		m.loadingCheck = true
 		m.loadingLeaf = true
+		m.witnessN = m.chosenWitnessN
+		if p := m.witnessPolicy(); p != nil && p.MinWitnesses() > 0 {
+			m.witnessN = p.MinWitnesses()
+		}
+	}
+}
+
+// setWitnessN sets the number of cosignatures the witnessed checkpoint
+// needs, which is kept for other logs unless it was for a witness policy.
+func (m *Model) setWitnessN(n uint) {
+	m.witnessN = n
+	if p := m.witnessPolicy(); p == nil || p.MinWitnesses() == 0 {
+		m.chosenWitnessN = n
+	}
+}
+
+// witnessPolicy returns the witness policy for the current log, or nil if
+// every witness known to the distributor is trusted equally.
+func (m *Model) witnessPolicy() *witnessPolicy {
+	if p, ok := m.witnessPolicies[m.currentLog]; ok {
+		return p
 	}
+	return m.witnessPolicies[""]
 }
 
 func (m *Model) fetchCheckpointCmd() tea.Cmd {
 	client := m.currentClient
-	distributor := m.distributor
+	distributors := m.distributors
 	witnessN := m.witnessN
-	witVerifiers := m.witVerifiers
+	policy := m.witnessPolicy()
+	witVerifiers := m.witnessVerifiers(policy)
 	return func() tea.Msg {
-		witnessed := make(chan *model.Checkpoint, 1)
+		witnessed := make(chan checkpointMsg, 1)
 		go func() {
 			defer close(witnessed)
-			logID := distclient.LogID(log.ID(client.GetOrigin()))
-			bs, err := distributor.GetCheckpointN(logID, witnessN)
-			if err != nil {
-				witnessed <- nil
-				return
-			}
-			cp, _, n, err := log.ParseCheckpoint(bs, client.GetOrigin(), client.GetVerifier(), witVerifiers...)
-			if err != nil {
-				witnessed <- nil
-				return
-			}
-			witnessed <- &model.Checkpoint{
-				Checkpoint: cp,
-				Note:       n,
-				Raw:        bs,
-			}
+			witnessed <- fetchWitnessed(distributors, client, witnessN, witVerifiers, policy)
 		}()
 
 		cp, err := client.GetCheckpoint()
-		wCP := <-witnessed
+		msg := <-witnessed
+		msg.origin = client.GetOrigin()
+		msg.checkpoint = cp
+		msg.err = err
+		return msg
+	}
+}
+
+// witnessVerifiers returns the verifiers for cosignatures from the trusted
+// witnesses and the witnesses named by policy, if any.
+func (m *Model) witnessVerifiers(policy *witnessPolicy) []note.Verifier {
+	if policy == nil {
+		return m.witVerifiers
+	}
+	return dedupeVerifiers(append(append([]note.Verifier{}, m.witVerifiers...), policy.Verifiers()...))
+}
+
+// fetchDashboardCmd refreshes every log for the dashboard.
+func (m *Model) fetchDashboardCmd() tea.Cmd {
+	targets := m.dashboardTargets()
+	distributors := m.distributors
+	return func() tea.Msg {
+		return dashboardMsg{results: refreshDashboard(targets, distributors)}
+	}
+}
+
+// dashboardTargets returns what to refresh for each log. Each log's
+// witnessed checkpoint is looked for using its own witness policy, or the
+// number of cosignatures chosen with w and W if it has none.
+func (m *Model) dashboardTargets() []dashboardTarget {
+	targets := make([]dashboardTarget, 0, len(m.logOrigins))
+	for _, o := range m.logOrigins {
+		policy, ok := m.witnessPolicies[o]
+		if !ok {
+			policy = m.witnessPolicies[""]
+		}
+		witnessN := m.chosenWitnessN
+		if policy != nil && policy.MinWitnesses() > 0 {
+			witnessN = policy.MinWitnesses()
+		}
+		targets = append(targets, dashboardTarget{
+			client:    m.logClients[o],
+			witnessN:  witnessN,
+			verifiers: m.witnessVerifiers(policy),
+			policy:    policy,
+		})
+	}
+	return targets
+}
+
+// renderDashboard sets the dashboard content, scrolling to keep the cursor
+// in view.
+func (m *Model) renderDashboard() {
+	m.dashboardView.SetContent(renderDashboard(m.logOrigins, m.dashboard, m.dashboardCursor, time.Now(), m.dashboardView.Width))
+	// The first line is the table header.
+	row := m.dashboardCursor + 1
+	if row-1 < m.dashboardView.YOffset {
+		m.dashboardView.SetYOffset(row - 1)
+	} else if row >= m.dashboardView.YOffset+m.dashboardView.Height {
+		m.dashboardView.SetYOffset(row - m.dashboardView.Height + 1)
+	}
+}
+
+// fetchFollowCmd streams the leaves added since the last leaf streamed.
+func (m *Model) fetchFollowCmd() tea.Cmd {
+	client := m.currentClient
+	checkpoint := m.checkpoint
+	from := m.followNext
+	m.followNext = checkpoint.Size
+	m.loadingFollow = true
+	return func() tea.Msg {
+		return fetchFollowLeaves(client, checkpoint, from)
+	}
+}
+
+// logHistory returns the checkpoint history of the log, creating it if
+// needed.
+func (m *Model) logHistory(origin string) *checkpointHistory {
+	h, ok := m.history[origin]
+	if !ok {
+		h = &checkpointHistory{}
+		m.history[origin] = h
+	}
+	return h
+}
+
+// recordHistory adds the current checkpoint to the log's history, along
+// with the number of cosignatures on the witnessed checkpoint.
+func (m *Model) recordHistory() {
+	h := m.logHistory(m.currentLog)
+	if m.checkpoint != nil && m.checkpoint.Checkpoint != nil {
+		h.observe(m.checkpoint.Size, m.checkpoint.Hash, time.Now())
+	}
+	if m.witnessed != nil {
+		h.witnessed(m.witnessed.Size, m.witnessed.Hash, len(m.witnessed.Note.Sigs)-1)
+	}
+	if m.activeView == "history" {
+		m.renderHistory()
+	}
+}
+
+// renderHistory sets the history content, scrolling to keep the cursor in
+// view.
+func (m *Model) renderHistory() {
+	m.historyView.SetContent(renderHistory(m.history[m.currentLog], m.historyCursor, time.Now()))
+	// The first line is the table header.
+	row := m.historyCursor + 1
+	if row-1 < m.historyView.YOffset {
+		m.historyView.SetYOffset(row - 1)
+	} else if row >= m.historyView.YOffset+m.historyView.Height {
+		m.historyView.SetYOffset(row - m.historyView.Height + 1)
+	}
+}
 
-		return checkpointMsg{
-			checkpoint: cp,
-			witnessed:  wCP,
-			err:        err,
+// fetchWitnessStatusCmd fetches each witness's view of the current log, by
+// probing the witnesses with URLs in the witness policy and asking the
+// distributors about the others.
+func (m *Model) fetchWitnessStatusCmd() tea.Cmd {
+	client := m.currentClient
+	checkpoint := m.checkpoint
+	distributors := m.distributors
+	targets := witnessTargets(m.witnessPolicy(), m.witVerifiers)
+	return func() tea.Msg {
+		return witnessStatusMsg{
+			origin:   client.GetOrigin(),
+			statuses: fetchWitnessStatuses(targets, distributors, client, checkpoint),
 		}
 	}
 }
 
 func (m *Model) fetchLeafCmd(index uint64) tea.Cmd {
 	checkpoint := m.checkpoint
+	witnessed := m.witnessed
+	proveWitnessed := m.proveWitnessed
 	client := m.currentClient
 	return func() tea.Msg {
 		if checkpoint == nil || checkpoint.Size == 0 {
@@ -209,15 +555,215 @@ func (m *Model) fetchLeafCmd(index uint64) tea.Cmd {
 		if index >= checkpoint.Size {
 			return leafMsg{err: fmt.Errorf("cannot fetch leaf bigger than checkpoint size %d", checkpoint.Size)}
 		}
-		leafBytes, err := client.GetLeaf(checkpoint, index)
-		return leafMsg{
-			leaf: model.Leaf{
-				Contents: leafBytes,
-				Index:    index,
-			},
-			err: err,
+		msg := leafMsg{checkpoint: checkpoint}
+		if proveWitnessed {
+			// Leaves beyond the witnessed checkpoint are still shown, proven
+			// against the log checkpoint, but flagged as not yet witnessed.
+			if witnessed != nil && index < witnessed.Size {
+				msg.checkpoint = witnessed
+				msg.witnessed = true
+			} else {
+				msg.notWitnessed = true
+			}
+		}
+		leaf, err := client.GetLeaf(msg.checkpoint, index)
+		if leaf == nil {
+			leaf = &model.Leaf{Index: index}
+		}
+		msg.leaf = *leaf
+		msg.err = err
+		return msg
+	}
+}
+
+// renderProof sets the proof panel content for the current proof state.
+func (m *Model) renderProof() {
+	switch {
+	case m.leaf.Proof != nil && m.leafCheckpoint != nil:
+		m.proofView.SetContent(renderAuditPath(m.leaf.Proof, m.leafCheckpoint.Hash))
+	case m.leafErr != nil:
+		m.proofView.SetContent(fmt.Sprintf("No inclusion proof: %v", m.leafErr))
+	default:
+		m.proofView.SetContent("No inclusion proof loaded.")
+	}
+}
+
+// renderWitnesses sets the witness panel content for the current witnessed
+// checkpoint, evaluating the witness policy if there is one.
+func (m *Model) renderWitnesses() {
+	var sb strings.Builder
+	if m.witnessed != nil {
+		fmt.Fprintf(&sb, "Witnessed size %d\n\n", m.witnessed.Size)
+		if len(m.witnessed.Note.Sigs) > 1 {
+			sb.WriteString("Cosignatures, freshest first:\n")
+			sb.WriteString(renderCosignatures(m.witnessed.Note, time.Now(), m.staleWitnessThreshold))
+			sb.WriteString("\n")
+		}
+	}
+	p := m.witnessPolicy()
+	switch {
+	case p != nil && m.policyNote != nil:
+		sb.WriteString(renderPolicyResult(p, m.policyResult, m.policyNote))
+	case p != nil:
+		fmt.Fprintf(&sb, "No checkpoint with %d cosignatures was found to evaluate the witness policy against.\n", m.witnessN)
+	case m.witnessed != nil:
+		sb.WriteString("No witness policy is configured, so every witness known to the distributor is trusted.\n")
+	default:
+		sb.WriteString("No witnessed signatures found at this level.\n")
+	}
+	if len(m.pinnedWitnesses) > 0 {
+		warn := lipgloss.NewStyle().Foreground(lipgloss.Color("#FBBF24"))
+		fmt.Fprintf(&sb, "\n%d pinned witnesses, combined with the distributors' witnesses by %s.\n", len(m.pinnedWitnesses), m.witnessMode)
+		if len(m.unknownWitnesses) > 0 {
+			sb.WriteString(warn.Render("⚠ Advertised by distributors but not pinned: "+strings.Join(m.unknownWitnesses, ", ")) + "\n")
+		}
+		if len(m.missingWitnesses) > 0 {
+			sb.WriteString(warn.Render("⚠ Pinned but not advertised by distributors: "+strings.Join(m.missingWitnesses, ", ")) + "\n")
+		}
+	}
+	sb.WriteString("\nWitness views of the log:\n")
+	switch {
+	case m.loadingWitnessStatus:
+		sb.WriteString(" Fetching...\n")
+	case len(m.witnessStatuses) == 0:
+		sb.WriteString(" No witnesses known.\n")
+	default:
+		sb.WriteString(renderWitnessStatuses(m.witnessStatuses, m.checkpoint, time.Now(), m.staleWitnessThreshold))
+	}
+	if len(m.distResults) > 0 {
+		sb.WriteString("\nDistributors:\n")
+		if m.splitView {
+			sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#F87171")).Render("⚠ Split view: distributors returned different checkpoints of the same size") + "\n")
+		}
+		for _, r := range m.distResults {
+			if r.err != nil {
+				fmt.Fprintf(&sb, " • %s: %v\n", r.name, r.err)
+				continue
+			}
+			fmt.Fprintf(&sb, " • %s: size %d, hash %s, %d cosignatures\n", r.name, r.size, shortHash(r.hash), r.cosigs)
+			for _, err := range r.invalid {
+				sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171")).Render(fmt.Sprintf("   ✗ %v", err)) + "\n")
+			}
 		}
 	}
+	m.witnessView.SetContent(sb.String())
+}
+
+// policySummary summarises the witness policy evaluation for the witnessed
+// checkpoint panel.
+func (m *Model) policySummary() string {
+	present, missing := m.policyResult.Witnesses()
+	var sb strings.Builder
+	if m.witnessed != nil {
+		fmt.Fprintf(&sb, "Size: %d\nHash: %x\n", m.witnessed.Size, m.witnessed.Hash)
+		sb.WriteString("Policy: ✓ satisfied\n")
+	} else {
+		sb.WriteString("Policy: ✗ not satisfied\n")
+	}
+	if len(present) > 0 {
+		sb.WriteString("Signed: " + strings.Join(present, ", ") + "\n")
+	}
+	if len(missing) > 0 {
+		sb.WriteString("Missing: " + strings.Join(missing, ", ") + "\n")
+	}
+	return sb.String()
+}
+
+// leafBadge summarises how the current leaf was verified.
+func (m *Model) leafBadge() string {
+	good := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#34D399"))
+	bad := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#F87171"))
+	warn := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FBBF24"))
+	switch {
+	case m.leaf.Verified && m.leafNotWitnessed:
+		if m.witnessed != nil {
+			return warn.Render(fmt.Sprintf("⧗ Not yet witnessed (witnessed size %d)", m.witnessed.Size))
+		}
+		return warn.Render("⧗ Not yet witnessed")
+	case m.leaf.Verified && m.leafWitnessed && m.leaf.Proof != nil:
+		return good.Render(fmt.Sprintf("✓ Verified in witnessed tree size %d", m.leaf.Proof.TreeSize))
+	case m.leaf.Verified && m.leaf.Proof != nil:
+		return good.Render(fmt.Sprintf("✓ Verified in tree size %d", m.leaf.Proof.TreeSize))
+	case m.leaf.Contents != nil:
+		return bad.Render("✗ Unverified")
+	case m.leafErr != nil:
+		return bad.Render("✗ Error")
+	}
+	return ""
+}
+
+// leafErrorLine returns the error for the current leaf, truncated to fit on
+// a single line of the given width.
+func (m *Model) leafErrorLine(width int) string {
+	if m.leafErr == nil || m.loadingLeaf {
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171")).Render(limitText(m.leafErr.Error(), width, 1))
+}
+
+// renderLeaf sets the viewport content for the current leaf using the
+// selected renderer. Leaves which are not valid JSON always use the text
+// renderer of the log client.
+func (m *Model) renderLeaf() {
+	m.jsonView = nil
+	text := m.currentClient.FormatLeaf(m.leaf.Contents)
+	useJSON := false
+	switch m.renderer {
+	case rendererJSON:
+		useJSON = true
+	case rendererAuto:
+		// Prefer the client's renderer if it did anything more than
+		// stringify the leaf (e.g. static-ct certificates).
+		useJSON = text == string(m.leaf.Contents)
+	}
+	if useJSON {
+		if root, err := parseJSON(m.leaf.Contents); err == nil {
+			m.jsonView = newJSONView(root)
+			m.viewport.SetContent(m.jsonView.Render())
+			return
+		}
+	}
+	m.viewport.SetContent(text)
+}
+
+// updateJSONView handles the keys for navigating a JSON leaf, returning
+// whether the key was consumed.
+func (m *Model) updateJSONView(keyMsg tea.KeyMsg) bool {
+	switch keyMsg.String() {
+	case "up", "k":
+		m.jsonView.MoveCursor(-1)
+	case "down", "j":
+		m.jsonView.MoveCursor(1)
+	case "pgup":
+		m.jsonView.MoveCursor(-m.viewport.Height)
+	case "pgdown":
+		m.jsonView.MoveCursor(m.viewport.Height)
+	case "enter", " ":
+		m.jsonView.ToggleFold()
+	case "-":
+		m.jsonView.SetAllFolded(true)
+	case "+", "=":
+		m.jsonView.SetAllFolded(false)
+	case "y":
+		path := m.jsonView.CurrentPath()
+		if err := copyToClipboard(path); err != nil {
+			m.statusMsg = fmt.Sprintf("Failed to copy %s: %v", path, err)
+		} else {
+			m.statusMsg = fmt.Sprintf("Copied %s", path)
+		}
+		return true
+	default:
+		return false
+	}
+	m.statusMsg = ""
+	m.viewport.SetContent(m.jsonView.Render())
+	// Scroll so that the cursor stays visible.
+	if c := m.jsonView.Cursor(); c < m.viewport.YOffset {
+		m.viewport.SetYOffset(c)
+	} else if c >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(c - m.viewport.Height + 1)
+	}
+	return true
 }
 
 func (m *Model) layoutHeights() (int, int) {
@@ -290,8 +836,202 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 
+	case "export":
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case "enter":
+				m.activeView = "leaf"
+				m.exporting = true
+				m.statusMsg = "Exporting..."
+				return m, m.exportCmd(m.exportInput.Value())
+			case "esc":
+				m.activeView = "leaf"
+				return m, nil
+			}
+		}
+		var cmd tea.Cmd
+		m.exportInput, cmd = m.exportInput.Update(msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case "verify":
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case "enter":
+				if m.verifyingTile {
+					return m, nil
+				}
+				m.verifyingTile = true
+				m.tileResult, m.tileErr = nil, nil
+				return m, m.verifyTileCmd(m.tileInput.Value())
+			case "esc":
+				m.activeView = "leaf"
+				return m, nil
+			}
+		}
+		var cmd tea.Cmd
+		m.tileInput, cmd = m.tileInput.Update(msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case "tiles":
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			return m.updateTileBrowser(keyMsg)
+		}
+
+	case "proof":
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case "q":
+				return m, tea.Quit
+			case "esc", "p":
+				m.activeView = "leaf"
+				return m, nil
+			case "left":
+				if m.leaf.Index > 0 && m.checkpoint != nil {
+					m.loadingLeaf = true
+					return m, m.fetchLeafCmd(m.leaf.Index - 1)
+				}
+			case "right":
+				if m.checkpoint != nil && m.leaf.Index+1 < m.checkpoint.Size {
+					m.loadingLeaf = true
+					return m, m.fetchLeafCmd(m.leaf.Index + 1)
+				}
+			}
+			var cmd tea.Cmd
+			m.proofView, cmd = m.proofView.Update(msg)
+			if cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+
+	case "witnesses":
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case "q":
+				return m, tea.Quit
+			case "esc", "i":
+				m.activeView = "leaf"
+				return m, nil
+			case "s":
+				m.loadingWitnessStatus = true
+				m.renderWitnesses()
+				return m, m.fetchWitnessStatusCmd()
+			}
+			var cmd tea.Cmd
+			m.witnessView, cmd = m.witnessView.Update(msg)
+			if cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+
+	case "dashboard":
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case "q":
+				return m, tea.Quit
+			case "esc", "d":
+				m.activeView = "leaf"
+				return m, nil
+			case "up", "k":
+				if m.dashboardCursor > 0 {
+					m.dashboardCursor--
+				}
+				m.renderDashboard()
+				return m, nil
+			case "down", "j":
+				if m.dashboardCursor < len(m.logOrigins)-1 {
+					m.dashboardCursor++
+				}
+				m.renderDashboard()
+				return m, nil
+			case "enter":
+				m.activeView = "leaf"
+				if o := m.logOrigins[m.dashboardCursor]; o != m.currentLog {
+					m.selectLog(o)
+					return m, m.fetchCheckpointCmd()
+				}
+				return m, nil
+			}
+		}
+
+	case "history":
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			h := m.history[m.currentLog]
+			switch keyMsg.String() {
+			case "q":
+				return m, tea.Quit
+			case "esc", "h":
+				m.activeView = "leaf"
+				return m, nil
+			case "up", "k":
+				if m.historyCursor > 0 {
+					m.historyCursor--
+				}
+				m.renderHistory()
+				return m, nil
+			case "down", "j":
+				if h != nil && m.historyCursor < len(h.entries)-1 {
+					m.historyCursor++
+				}
+				m.renderHistory()
+				return m, nil
+			case "enter":
+				// Rows are newest first.
+				if h == nil {
+					return m, nil
+				}
+				idx, ok := h.firstNewLeaf(len(h.entries) - 1 - m.historyCursor)
+				if ok && m.checkpoint != nil && idx < m.checkpoint.Size {
+					m.activeView = "leaf"
+					m.loadingLeaf = true
+					return m, m.fetchLeafCmd(idx)
+				}
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.historyView, cmd = m.historyView.Update(msg)
+			if cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+
+	case "stats":
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case "q":
+				return m, tea.Quit
+			case "esc", "D":
+				m.activeView = "leaf"
+				return m, nil
+			}
+		}
+
+	case "follow":
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.String() {
+			case "q":
+				return m, tea.Quit
+			case "esc", "f":
+				m.activeView = "leaf"
+				// Resume manual navigation from the newest leaf streamed.
+				for i := len(m.followLeaves) - 1; i >= 0; i-- {
+					if l := m.followLeaves[i]; l.skipped == 0 {
+						m.loadingLeaf = true
+						return m, m.fetchLeafCmd(l.index)
+					}
+				}
+				return m, nil
+			}
+		}
+
 	case "leaf":
 		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			if m.jsonView != nil && m.updateJSONView(keyMsg) {
+				return m, nil
+			}
 			switch keyMsg.String() {
 			case "q":
 				return m, tea.Quit
@@ -313,13 +1053,88 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.textInput.Reset()
 				m.textInput.Focus()
 				return m, textinput.Blink
+			case "p":
+				m.activeView = "proof"
+				m.renderProof()
+				return m, nil
+			case "e":
+				if m.checkpoint == nil || m.exporting {
+					return m, nil
+				}
+				m.activeView = "export"
+				m.exportInput.SetValue(exportDefaults(m.currentLog, m.leaf.Index, m.checkpoint.Size))
+				m.exportInput.CursorEnd()
+				m.exportInput.Width = max(m.width-10, 20)
+				m.exportInput.Focus()
+				return m, textinput.Blink
+			case "t":
+				if m.checkpoint == nil || m.checkpoint.Size == 0 {
+					return m, nil
+				}
+				m.activeView = "tiles"
+				m.tileGoto = false
+				m.tileBrowserErr = nil
+				return m, m.loadTileCmd(0, m.leaf.Index/256, false, int(m.leaf.Index%256))
+			case "V":
+				m.activeView = "verify"
+				m.tileInput.SetValue(fmt.Sprintf("0 %d", m.leaf.Index/256))
+				m.tileInput.CursorEnd()
+				m.tileInput.Focus()
+				return m, textinput.Blink
+			case "D":
+				m.activeView = "stats"
+				return m, nil
+			case "f":
+				if m.checkpoint == nil {
+					return m, nil
+				}
+				m.activeView = "follow"
+				m.followLeaves = nil
+				m.followNext = m.checkpoint.Size
+				m.followStart = time.Now()
+				m.followStartSize = m.checkpoint.Size
+				return m, nil
+			case "h":
+				m.activeView = "history"
+				m.historyCursor = 0
+				m.renderHistory()
+				return m, nil
+			case "d":
+				m.activeView = "dashboard"
+				for i, o := range m.logOrigins {
+					if o == m.currentLog {
+						m.dashboardCursor = i
+					}
+				}
+				m.renderDashboard()
+				if m.loadingDashboard {
+					return m, nil
+				}
+				m.loadingDashboard = true
+				return m, m.fetchDashboardCmd()
+			case "i":
+				m.activeView = "witnesses"
+				m.loadingWitnessStatus = true
+				m.renderWitnesses()
+				return m, m.fetchWitnessStatusCmd()
+			case "r":
+				m.renderer = nextRenderer(m.renderer)
+				m.renderLeaf()
+				return m, nil
+			case "v":
+				m.proveWitnessed = !m.proveWitnessed
+				if m.checkpoint != nil && m.leaf.Index < m.checkpoint.Size {
+					m.loadingLeaf = true
+					return m, m.fetchLeafCmd(m.leaf.Index)
+				}
+				return m, nil
 			case "w":
-				m.witnessN++
+				m.setWitnessN(m.witnessN + 1)
 				m.loadingCheck = true
 				return m, m.fetchCheckpointCmd()
 			case "W":
 				if m.witnessN > 1 {
-					m.witnessN--
+					m.setWitnessN(m.witnessN - 1)
 					m.loadingCheck = true
 					return m, m.fetchCheckpointCmd()
 				}
@@ -347,36 +1162,172 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.viewport.Width = m.width - 6
 		m.viewport.Height = vpHeight
+		m.proofView.Width = m.width - 6
+		m.proofView.Height = vpHeight
+		m.witnessView.Width = m.width - 6
+		m.witnessView.Height = vpHeight
+		m.dashboardView.Width = m.width - 6
+		m.dashboardView.Height = vpHeight
+		m.historyView.Width = m.width - 6
+		m.historyView.Height = vpHeight
+		m.tileBrowserView.Width = m.width - 6
+		m.tileBrowserView.Height = max(vpHeight-3, 1)
 		m.list.SetSize(msg.Width-6, vpHeight)
 
 	case tickMsg:
-		return m, tea.Batch(append(cmds, m.fetchCheckpointCmd(), m.startPeriodicTicker())...)
+		if msg.gen != m.tickGen {
+			break
+		}
+		cmds = append(cmds, m.fetchCheckpointCmd())
+		if m.activeView == "dashboard" && !m.loadingDashboard {
+			m.loadingDashboard = true
+			cmds = append(cmds, m.fetchDashboardCmd())
+		}
+		return m, tea.Batch(cmds...)
+
+	case dashboardMsg:
+		m.loadingDashboard = false
+		for _, r := range msg.results {
+			h, ok := m.dashboard[r.origin]
+			if !ok {
+				h = &logHealth{}
+				m.dashboard[r.origin] = h
+			}
+			h.update(r)
+			if r.err == nil {
+				history := m.logHistory(r.origin)
+				history.observe(r.size, r.hash, r.at)
+				history.witnessed(r.witnessedSize, r.witnessedHash, r.witnesses)
+			}
+		}
+		m.renderDashboard()
+
+	case witnessesMsg:
+		m.witnessesErr = msg.err
+		if msg.err != nil {
+			klog.Warningf("Distributor unavailable: %v", msg.err)
+			return m, tea.Batch(append(cmds, tea.Tick(witnessRetryInterval, func(time.Time) tea.Msg {
+				return retryWitnessesMsg{}
+			}))...)
+		}
+		m.witVerifiers = msg.verifiers
+		m.witnessesFetched = true
+		m.unknownWitnesses = msg.unknown
+		m.missingWitnesses = msg.missing
+		if len(msg.unknown) > 0 {
+			klog.Warningf("Distributors advertise witnesses that are not pinned: %s", strings.Join(msg.unknown, ", "))
+		}
+		if len(msg.missing) > 0 {
+			klog.Warningf("Pinned witnesses not advertised by the distributors: %s", strings.Join(msg.missing, ", "))
+		}
+		// Now that cosignatures can be verified, look for a witnessed
+		// checkpoint straight away rather than waiting for the next tick.
+		return m, tea.Batch(append(cmds, m.fetchCheckpointCmd())...)
+
+	case witnessStatusMsg:
+		if msg.origin == m.currentLog {
+			m.loadingWitnessStatus = false
+			m.witnessStatuses = msg.statuses
+			m.renderWitnesses()
+		}
+
+	case retryWitnessesMsg:
+		return m, tea.Batch(append(cmds, m.fetchWitnessesCmd())...)
 
 	case checkpointMsg:
+		// A checkpoint fetched before switching logs is for the old log.
+		if msg.origin != m.currentLog {
+			break
+		}
 		m.loadingCheck = false
 		m.activeErr = msg.err
+		if msg.err != nil {
+			m.refreshFailures++
+		} else {
+			m.refreshFailures = 0
+		}
+		cmds = append(cmds, m.startPeriodicTicker())
 		if msg.err == nil {
 			m.checkpoint = msg.checkpoint
 			m.witnessed = msg.witnessed
+			m.policyResult = msg.policy
+			m.policyNote = msg.cosigned
+			m.distResults = msg.distributors
+			m.splitView = msg.splitView
+			m.recordHistory()
+			m.renderWitnesses()
+			if m.activeView == "follow" && !m.loadingFollow && m.checkpoint != nil && m.checkpoint.Size > m.followNext {
+				cmds = append(cmds, m.fetchFollowCmd())
+			}
 			// Load the last leaf if none is loaded or index is out of bounds
-			if m.leaf.Contents == nil || (m.checkpoint != nil && m.leaf.Index >= m.checkpoint.Size) {
+			if (m.leaf.Contents == nil && m.leafErr == nil) || (m.checkpoint != nil && m.leaf.Index >= m.checkpoint.Size) {
 				if m.checkpoint != nil && m.checkpoint.Size > 0 {
 					m.loadingLeaf = true
 					return m, tea.Batch(append(cmds, m.fetchLeafCmd(m.checkpoint.Size-1))...)
 				}
 			}
+			// Re-prove the current leaf once the witnessed checkpoint covers it.
+			if m.proveWitnessed && m.leafNotWitnessed && !m.loadingLeaf && m.witnessed != nil && m.leaf.Index < m.witnessed.Size {
+				m.loadingLeaf = true
+				return m, tea.Batch(append(cmds, m.fetchLeafCmd(m.leaf.Index))...)
+			}
 		}
 
-	case leafMsg:
-		m.loadingLeaf = false
-		m.activeErr = msg.err
-		if msg.err == nil {
-			m.leaf = msg.leaf
-			m.viewport.SetContent(m.currentClient.FormatLeaf(msg.leaf.Contents))
+	case followLeavesMsg:
+		m.loadingFollow = false
+		if msg.origin != m.currentLog {
+			break
+		}
+		if msg.skipped > 0 {
+			m.followLeaves = append(m.followLeaves, followLeaf{skipped: msg.skipped})
+		}
+		m.followLeaves = append(m.followLeaves, msg.leaves...)
+		if len(m.followLeaves) > maxFollowLeaves {
+			m.followLeaves = m.followLeaves[len(m.followLeaves)-maxFollowLeaves:]
+		}
+		// The log may have grown while these leaves were fetched.
+		if m.activeView == "follow" && m.checkpoint != nil && m.checkpoint.Size > m.followNext {
+			cmds = append(cmds, m.fetchFollowCmd())
+		}
+
+	case exportDoneMsg:
+		m.exporting = false
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Export failed after %d leaves: %v", msg.n, msg.err)
 		} else {
-			m.viewport.SetContent(fmt.Sprintf("Error fetching leaf: %v", msg.err))
+			m.statusMsg = fmt.Sprintf("Exported %d leaves, verified in tree size %d, as %s to %s", msg.n, msg.size, msg.format, msg.out)
+		}
+
+	case tileBrowserMsg:
+		if msg.origin != m.currentLog {
+			break
+		}
+		m.loadingTile = false
+		m.tileBrowserErr = msg.err
+		if msg.err == nil {
+			m.tileBrowser = msg.tile
+			m.tileBrowserView.GotoTop()
+			m.renderTileBrowser()
 		}
 
+	case tileVerifiedMsg:
+		m.verifyingTile = false
+		if msg.origin == m.currentLog {
+			m.tileResult, m.tileErr = msg.result, msg.err
+		}
+
+	case leafMsg:
+		m.loadingLeaf = false
+		m.leaf = msg.leaf
+		m.leafCheckpoint = msg.checkpoint
+		m.leafWitnessed = msg.witnessed
+		m.leafNotWitnessed = msg.notWitnessed
+		m.leafErr = msg.err
+		m.statusMsg = ""
+		m.renderLeaf()
+		m.viewport.GotoTop()
+		m.renderProof()
+
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -449,37 +1400,80 @@ func (m *Model) View() string {
 		usableWidth = 10
 	}
 
-	cpText = limitText(cpText, usableWidth, maxContentLines)
+	if h := m.history[m.currentLog]; h != nil && len(h.entries) > 1 && maxContentLines > 1 && !m.loadingCheck {
+		// The sparkline is styled after truncating, as limitText counts
+		// bytes rather than runes.
+		spark := lipgloss.NewStyle().Foreground(lipgloss.Color("#A78BFA")).Render(h.sparkline(usableWidth))
+		cpText = limitText(cpText, usableWidth, maxContentLines-1) + "\n" + spark
+	} else {
+		cpText = limitText(cpText, usableWidth, maxContentLines)
+	}
 
 	var witnessedText string
 	if m.loadingCheck {
 		witnessedText = fmt.Sprintf("%s Fetching witnessed checkpoint...", m.spinner.View())
+	} else if m.policyResult != nil {
+		witnessedText = m.policySummary()
 	} else if m.witnessed != nil {
 		var wsb strings.Builder
 		fmt.Fprintf(&wsb, "Size: %d\nHash: %x\n", m.witnessed.Size, m.witnessed.Hash)
 		if len(m.witnessed.Note.Sigs) > 1 {
 			wsb.WriteString("Witnesses:\n")
-			for _, w := range m.witnessed.Note.Sigs[1:] {
-				wsb.WriteString(" • " + w.Name + "\n")
+			now := time.Now()
+			for _, c := range cosignatures(m.witnessed.Note) {
+				wsb.WriteString(cosignatureLine(c, now, m.staleWitnessThreshold) + "\n")
 			}
 		}
 		witnessedText = wsb.String()
+	} else if len(m.distributors) == 0 {
+		witnessedText = "Witnessed checkpoints are disabled."
+	} else if m.witnessesErr != nil {
+		witnessedText = fmt.Sprintf("Distributor unavailable, retrying in the background.\n%v", m.witnessesErr)
 	} else {
 		witnessedText = "No witnessed signatures found at this level."
 	}
 
-	witnessedText = limitText(witnessedText, usableWidth, maxContentLines)
+	if m.splitView && !m.loadingCheck {
+		// Style the warning after truncating, as limitText counts the bytes
+		// of escape sequences.
+		warning := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#F87171")).Render(limitText("⚠ Split view between distributors", usableWidth, 1))
+		witnessedText = warning + "\n" + limitText(witnessedText, usableWidth, maxContentLines-1)
+	} else {
+		witnessedText = limitText(witnessedText, usableWidth, maxContentLines)
+	}
 
+	checkpointTitle := fmt.Sprintf("Checkpoint: %s", m.currentLog)
+	if status := m.refreshStatus(time.Now()); status != "" && !m.loadingCheck {
+		// Keep the refresh status visible by truncating the origin.
+		status = " • " + status
+		checkpointTitle = limitText(checkpointTitle, max(usableWidth-len(status), 12), 1) + status
+	}
 	leftPanel := panelStyle.Render(
 		lipgloss.JoinVertical(lipgloss.Left,
-			lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#A78BFA")).Render(fmt.Sprintf("Checkpoint: %s", m.currentLog)),
+			lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#A78BFA")).Render(limitText(checkpointTitle, usableWidth, 1)),
 			cpText,
 		),
 	)
 
+	witnessedTitle := fmt.Sprintf("Witnessed Checkpoint (N=%d)", m.witnessN)
+	if m.witnessPolicy() != nil {
+		witnessedTitle = fmt.Sprintf("Witnessed Checkpoint (N=%d, policy)", m.witnessN)
+	}
+	if len(m.distributors) > 1 && m.distResults != nil {
+		ok := 0
+		for _, r := range m.distResults {
+			if r.err == nil {
+				ok++
+			}
+		}
+		witnessedTitle += fmt.Sprintf(" • %d/%d distributors", ok, len(m.distResults))
+	}
+	if m.proveWitnessed {
+		witnessedTitle += " • Proving Leaves"
+	}
 	rightPanel := accentPanelStyle.Render(
 		lipgloss.JoinVertical(lipgloss.Left,
-			lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render(fmt.Sprintf("Witnessed Checkpoint (N=%d)", m.witnessN)),
+			lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render(limitText(witnessedTitle, usableWidth, 1)),
 			witnessedText,
 		),
 	)
@@ -499,6 +1493,67 @@ func (m *Model) View() string {
 	switch m.activeView {
 	case "logs":
 		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#4F46E5")).Render(m.list.View()))
+	case "proof":
+		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render(fmt.Sprintf("Inclusion Proof: Leaf %d", m.leaf.Index)),
+				"",
+				m.proofView.View(),
+			),
+		))
+	case "stats":
+		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render("HTTP Statistics"),
+				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render("[D/esc] Back"),
+				"",
+				limitText(m.fetchStats.render(), m.width-6, max(viewportHeight-5, 1)),
+			),
+		))
+	case "follow":
+		rate := 0.0
+		if m.checkpoint != nil {
+			rate = followRate(m.followStartSize, m.checkpoint.Size, m.followStart, time.Now())
+		}
+		streamHeight := viewportHeight - 5
+		if streamHeight < 1 {
+			streamHeight = 1
+		}
+		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render(fmt.Sprintf("Following new leaves  •  %.2f leaves/s", rate)),
+				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render("[f/esc] Stop following"),
+				"",
+				renderFollow(m.followLeaves, m.width-6, streamHeight),
+			),
+		))
+	case "history":
+		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render(fmt.Sprintf("Checkpoint History: %s", m.currentLog)),
+				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render("[↑/↓] Select  •  [Enter] Jump to first new leaf  •  [esc] Back"),
+				"",
+				m.historyView.View(),
+			),
+		))
+	case "dashboard":
+		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render("Dashboard"),
+				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render("[↑/↓] Select  •  [Enter] Open log  •  [esc] Back"),
+				"",
+				m.dashboardView.View(),
+			),
+		))
+	case "witnesses":
+		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render("Witnesses"),
+				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render("[s] Refresh witness views  •  [esc] Back"),
+				"",
+				m.witnessView.View(),
+			),
+		))
 	case "jump":
 		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
 			lipgloss.JoinVertical(lipgloss.Left,
@@ -509,6 +1564,60 @@ func (m *Model) View() string {
 				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render("Press Enter to jump, Escape to cancel"),
 			),
 		))
+	case "tiles":
+		hint := "[↑/↓] Select  •  [←/→] Prev/Next tile  •  [Enter] Drill down  •  [u] Up  •  [g] Go to tile  •  [V] Verify  •  [esc] Back"
+		var header string
+		switch {
+		case m.tileGoto:
+			header = "Go to LEVEL INDEX: " + m.tileInput.View()
+		case m.loadingTile:
+			header = m.spinner.View() + " Loading tile..."
+		case m.tileBrowserErr != nil:
+			header = lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171")).Render(limitText(m.tileBrowserErr.Error(), m.width-6, 1))
+		}
+		body := ""
+		if m.tileBrowser != nil && m.checkpoint != nil {
+			body = m.tileBrowser.header(m.checkpoint.Size) + "\n" + m.tileBrowserView.View()
+		}
+		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render(fmt.Sprintf("Tile Browser: %s", m.currentLog)),
+				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render(limitText(hint, m.width-6, 1)),
+				header,
+				body,
+			),
+		))
+	case "verify":
+		var result string
+		switch {
+		case m.verifyingTile:
+			result = m.spinner.View() + " Verifying..."
+		case m.tileErr != nil:
+			result = lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171")).Render(limitText(m.tileErr.Error(), m.width-6, 3))
+		case m.tileResult != nil:
+			result = m.tileResult.render()
+		}
+		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render("Verify Hash Tile"),
+				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render("LEVEL INDEX  •  [Enter] Verify  •  [esc] Back"),
+				"",
+				m.tileInput.View(),
+				"",
+				result,
+			),
+		))
+	case "export":
+		sb.WriteString(mainBoxStyle.BorderForeground(lipgloss.Color("#14B8A6")).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2DD4BF")).Render("Export Verified Leaves"),
+				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render("START END FORMAT OUT, where FORMAT is jsonl, csv or files and END is exclusive"),
+				"",
+				m.exportInput.View(),
+				"",
+				lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#6B7280")).Render("Press Enter to export, Escape to cancel"),
+			),
+		))
 	default:
 		var leafTitle string
 		if m.loadingLeaf {
@@ -516,11 +1625,29 @@ func (m *Model) View() string {
 		} else {
 			leafTitle = fmt.Sprintf("Leaf %d", m.leaf.Index)
 		}
+		leafTitle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#C084FC")).Render(leafTitle)
+		if !m.loadingLeaf {
+			if badge := m.leafBadge(); badge != "" {
+				leafTitle += "  " + badge
+			}
+		}
+		var leafInfo string
+		switch {
+		case m.statusMsg != "":
+			leafInfo = m.statusMsg
+		case m.jsonView != nil:
+			leafInfo = fmt.Sprintf("[json] %s", m.jsonView.CurrentPath())
+		case m.renderer != rendererAuto:
+			leafInfo = fmt.Sprintf("[%s]", m.renderer)
+		}
+		if leafInfo != "" {
+			leafTitle += "  " + lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280")).Render(leafInfo)
+		}
 
 		sb.WriteString(mainBoxStyle.Render(
 			lipgloss.JoinVertical(lipgloss.Left,
-				lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#C084FC")).Render(leafTitle),
-				"",
+				leafTitle,
+				m.leafErrorLine(m.width-8),
 				m.viewport.View(),
 			),
 		))
@@ -532,11 +1659,47 @@ func (m *Model) View() string {
 		Foreground(lipgloss.Color("#6B7280")).
 		Italic(true)
 
-	sb.WriteString(footerStyle.Render(" [q] Quit  •  [←/→] Prev/Next Leaf  •  [↑/↓] Scroll Content  •  [l] Switch Log  •  [g] Jump  •  [w/W] Witnesses"))
+	sb.WriteString(footerStyle.Render(fitKeys(m.footerKeys(), m.width-2)))
 
 	return sb.String()
 }
 
+// footerKeys returns the keys of the active view for the footer, most
+// useful first. Views other than the leaf view list their own keys in
+// their panel, so only the ways out are shown for them.
+func (m *Model) footerKeys() []string {
+	switch m.activeView {
+	case "leaf":
+		return []string{"[q] Quit", "[←/→] Prev/Next Leaf", "[↑/↓] Scroll Content", "[l] Switch Log", "[g] Jump",
+			"[p] Proof", "[r] Renderer", "[i] Witness Info", "[w/W] Witness Count", "[v] Prove Against Witnessed",
+			"[e] Export", "[f] Follow", "[h] History", "[d] Dashboard", "[t] Tiles", "[V] Verify Tile", "[D] HTTP Stats"}
+	case "proof":
+		return []string{"[q] Quit", "[←/→] Prev/Next Leaf", "[↑/↓] Scroll Proof", "[p/esc] Back"}
+	case "logs":
+		return []string{"[enter] Select", "[/] Search", "[esc] Back"}
+	case "jump", "export", "verify":
+		return []string{"[esc] Cancel", "[ctrl+c] Quit"}
+	}
+	return []string{"[q] Quit", "[esc] Back"}
+}
+
+// fitKeys joins as many of keys as fit on a line of the given width, so
+// that the footer never wraps.
+func fitKeys(keys []string, width int) string {
+	line := ""
+	for _, k := range keys {
+		next := " " + k
+		if line != "" {
+			next = line + "  •  " + k
+		}
+		if lipgloss.Width(next) > width {
+			break
+		}
+		line = next
+	}
+	return line
+}
+
 func mergeIndices(existing, newIndices []int) []int {
 	m := make(map[int]bool)
 	for _, idx := range existing {
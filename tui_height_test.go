@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/mhutchinson/woodpecker/model"
 	"github.com/transparency-dev/formats/log"
 )
@@ -29,9 +30,10 @@ func TestTUIHeightAndContent(t *testing.T) {
 			m := NewModel(
 				[]string{"test-log"},
 				clients,
-				&mockDistributor{},
+				mockDistributors(&mockDistributor{}),
 				nil,
 				"test-log",
+				modelOptions{},
 			)
 
 			// Set window size
@@ -53,19 +55,57 @@ func TestTUIHeightAndContent(t *testing.T) {
 			}
 			m.viewport.SetContent(string(m.leaf.Contents))
 
-			// Get the TUI View
-			view := m.View()
-			lines := strings.Split(view, "\n")
+			// Get the TUI View, in the views whose panels shrink to fit.
+			// Lines wider than the terminal wrap, so rows are counted at
+			// its width.
+			for _, view := range []string{"leaf", "proof"} {
+				m.activeView = view
+				out := m.View()
+				rows := renderedRows(out, m.width)
 
-			t.Logf("Terminal Height: %d", m.height)
-			t.Logf("Rendered TUI lines: %d", len(lines))
+				t.Logf("Terminal Height: %d", m.height)
+				t.Logf("Rendered TUI rows in %s view: %d", view, rows)
 
-			if len(lines) > m.height {
-				for i, l := range lines {
-					t.Logf("%02d: %q", i+1, l)
+				if rows > m.height {
+					for i, l := range strings.Split(out, "\n") {
+						t.Logf("%02d: %q", i+1, l)
+					}
+					t.Errorf("FAIL: Rendered rows (%d) in %s view exceeded terminal height (%d)!", rows, view, m.height)
 				}
-				t.Errorf("FAIL: Rendered lines (%d) exceeded terminal height (%d)!", len(lines), m.height)
 			}
 		})
 	}
 }
+
+// renderedRows returns the number of terminal rows that view takes up at
+// the given width, counting lines which wrap.
+func renderedRows(view string, width int) int {
+	rows := 0
+	for _, l := range strings.Split(view, "\n") {
+		rows += max(1, (lipgloss.Width(l)+width-1)/width)
+	}
+	return rows
+}
+
+func TestFooterKeys(t *testing.T) {
+	m := NewModel([]string{"test-log"}, map[string]logClient{"test-log": &mockLogClient{}}, nil, nil, "test-log", modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	footer := fitKeys(m.footerKeys(), m.width-2)
+	if lipgloss.Width(footer) > m.width-2 || !strings.Contains(footer, "[q] Quit") {
+		t.Errorf("footer %q doesn't fit in %d columns", footer, m.width-2)
+	}
+	seen := make(map[string]bool)
+	for _, k := range m.footerKeys() {
+		label := k[strings.Index(k, "]")+1:]
+		if seen[label] {
+			t.Errorf("two keys are labelled %q", label)
+		}
+		seen[label] = true
+	}
+
+	m.activeView = "history"
+	if got := fitKeys(m.footerKeys(), m.width-2); strings.Contains(got, "Prev/Next Leaf") {
+		t.Errorf("history footer %q shows keys of the leaf view", got)
+	}
+}
@@ -21,7 +21,7 @@ type customMockClient struct {
 func (c *customMockClient) GetOrigin() string                         { return c.origin }
 func (c *customMockClient) GetVerifier() note.Verifier                { return nil }
 func (c *customMockClient) GetCheckpoint() (*model.Checkpoint, error) { return nil, nil }
-func (c *customMockClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) ([]byte, error) {
+func (c *customMockClient) GetLeaf(checkpoint *model.Checkpoint, index uint64) (*model.Leaf, error) {
 	return nil, nil
 }
 func (c *customMockClient) FormatLeaf(leaf []byte) string { return "" }
@@ -29,8 +29,28 @@ func (c *customMockClient) GetLogType() string            { return c.logType }
 func (c *customMockClient) GetURL() string                { return c.url }
 
 type mockDistributor struct {
-	checkpoint []byte
-	err        error
+	checkpoint   []byte
+	err          error
+	witnesses    []string
+	witnessesErr error
+	// byWitness holds the latest checkpoint cosigned by each witness.
+	byWitness map[string][]byte
+}
+
+func (m *mockDistributor) GetCheckpointWitness(l distclient.LogID, w string) ([]byte, error) {
+	if cp, ok := m.byWitness[w]; ok {
+		return cp, nil
+	}
+	return nil, errors.New("mock distributor: no checkpoint for witness")
+}
+
+// mockDistributors returns d as the only distributor for a model.
+func mockDistributors(d *mockDistributor) []distributor {
+	return []distributor{{name: "mock", client: d}}
+}
+
+func (m *mockDistributor) GetWitnesses() ([]string, error) {
+	return m.witnesses, m.witnessesErr
 }
 
 func (m *mockDistributor) GetCheckpointN(l distclient.LogID, n uint) ([]byte, error) {
@@ -124,7 +144,7 @@ func TestTUIFilteringIntegration(t *testing.T) {
 		origins = append(origins, c.origin)
 	}
 
-	m := NewModel(origins, clientsMap, &mockDistributor{}, nil, "go.sum database tree")
+	m := NewModel(origins, clientsMap, mockDistributors(&mockDistributor{}), nil, "go.sum database tree", modelOptions{})
 	m.activeView = "leaf"
 
 	// 1. Open the log picker view ('l')
@@ -200,7 +220,7 @@ func TestTUIFilteringAndSelectionIntegration(t *testing.T) {
 		origins = append(origins, c.origin)
 	}
 
-	m := NewModel(origins, clientsMap, &mockDistributor{}, nil, "go.sum database tree")
+	m := NewModel(origins, clientsMap, mockDistributors(&mockDistributor{}), nil, "go.sum database tree", modelOptions{})
 	m.activeView = "leaf"
 
 	// 1. Open log picker
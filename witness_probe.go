@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mhutchinson/woodpecker/model"
+	distclient "github.com/transparency-dev/distributor/client"
+	"github.com/transparency-dev/formats/log"
+	tnote "github.com/transparency-dev/formats/note"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// witnessStatus is a single witness's latest view of a log.
+type witnessStatus struct {
+	name string
+	// source is where the view came from: "probe" if the witness was asked
+	// directly, or the name of the distributor that published it.
+	source string
+	size   uint64
+	// timestamp is from the witness's cosignature, and is zero if the
+	// witness only reported its size.
+	timestamp time.Time
+	err       error
+}
+
+// witnessTarget is a witness whose view of a log can be fetched, either
+// directly from its tlog-witness URL or from the distributors.
+type witnessTarget struct {
+	verifier note.Verifier
+	url      string
+}
+
+// witnessTargets returns the witnesses named by the policy, followed by any
+// other witnesses known to the distributors.
+func witnessTargets(policy *witnessPolicy, verifiers []note.Verifier) []witnessTarget {
+	var ts []witnessTarget
+	seen := make(map[string]bool)
+	add := func(v note.Verifier, url string) {
+		k := fmt.Sprintf("%s+%08x", v.Name(), v.KeyHash())
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		ts = append(ts, witnessTarget{verifier: v, url: url})
+	}
+	if policy != nil {
+		for _, w := range policy.witnesses {
+			add(w.verifier, w.url)
+		}
+	}
+	for _, v := range verifiers {
+		add(v, "")
+	}
+	return ts
+}
+
+// fetchWitnessStatuses fetches each witness's view of the log in parallel.
+// Witnesses with a URL are probed directly, and the rest are looked up in
+// the distributors.
+func fetchWitnessStatuses(targets []witnessTarget, ds []distributor, client logClient, cp *model.Checkpoint) []witnessStatus {
+	statuses := make([]witnessStatus, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if t.url != "" {
+				statuses[i] = probeWitness(context.Background(), t.url, t.verifier, cp)
+				return
+			}
+			statuses[i] = distributorWitnessStatus(ds, client, t.verifier)
+		}()
+	}
+	wg.Wait()
+	sortWitnessStatuses(statuses)
+	return statuses
+}
+
+// sortWitnessStatuses orders statuses from the freshest view of the log:
+// by cosignature timestamp, then size, then name. Failed lookups are last.
+func sortWitnessStatuses(statuses []witnessStatus) {
+	sort.SliceStable(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		if (a.err == nil) != (b.err == nil) {
+			return a.err == nil
+		}
+		if !a.timestamp.Equal(b.timestamp) {
+			return a.timestamp.After(b.timestamp)
+		}
+		if a.size != b.size {
+			return a.size > b.size
+		}
+		return a.name < b.name
+	})
+}
+
+// probeWitness asks the witness at the tlog-witness URL witnessURL for its
+// view of the log, without changing its state.
+//
+// It sends an add-checkpoint request for cp claiming that the witness
+// already has cp's size, with no consistency proof. If that is the
+// witness's latest size then it cosigns cp again, and otherwise it rejects
+// the request with a 409 Conflict containing its latest size. In neither
+// case does the witness's view of the log advance.
+func probeWitness(ctx context.Context, witnessURL string, v note.Verifier, cp *model.Checkpoint) witnessStatus {
+	s := witnessStatus{name: v.Name(), source: "probe"}
+	if cp == nil || cp.Note == nil {
+		s.err = fmt.Errorf("no log checkpoint to probe with")
+		return s
+	}
+	u := strings.TrimSuffix(witnessURL, "/") + "/add-checkpoint"
+	body := fmt.Sprintf("old %d\n\n%s", cp.Size, cp.Raw)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(body))
+	if err != nil {
+		s.err = err
+		return s
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The body is the witness's cosignature lines for cp.
+		signed := append([]byte(cp.Note.Text+"\n"), respBody...)
+		n, err := note.Open(signed, note.VerifierList(v))
+		if err != nil {
+			s.err = fmt.Errorf("invalid cosignature: %w", err)
+			return s
+		}
+		s.size = cp.Size
+		s.timestamp, s.err = tnote.CoSigV1Timestamp(n.Sigs[0])
+	case http.StatusConflict:
+		size, err := strconv.ParseUint(strings.TrimSpace(string(respBody)), 10, 64)
+		if err != nil {
+			s.err = fmt.Errorf("invalid size in conflict response: %q", respBody)
+			return s
+		}
+		s.size = size
+	case http.StatusNotFound:
+		s.err = fmt.Errorf("witness does not know this log")
+	default:
+		s.err = fmt.Errorf("unexpected status %q: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+	return s
+}
+
+// distributorWitnessStatus returns the latest checkpoint cosigned by the
+// witness, as published by the first distributor that has one.
+func distributorWitnessStatus(ds []distributor, client logClient, v note.Verifier) witnessStatus {
+	s := witnessStatus{name: v.Name()}
+	logID := distclient.LogID(log.ID(client.GetOrigin()))
+	for _, d := range ds {
+		bs, err := d.client.GetCheckpointWitness(logID, v.Name())
+		if err != nil {
+			s.err = err
+			continue
+		}
+		cp, _, n, err := log.ParseCheckpoint(bs, client.GetOrigin(), client.GetVerifier(), v)
+		if err != nil {
+			s.err = err
+			continue
+		}
+		for _, sig := range n.Sigs {
+			if sig.Name == v.Name() && sig.Hash == v.KeyHash() {
+				s.source = d.name
+				s.size = cp.Size
+				s.timestamp, s.err = tnote.CoSigV1Timestamp(sig)
+				return s
+			}
+		}
+		s.err = fmt.Errorf("checkpoint from %s is not cosigned by the witness", d.name)
+	}
+	if s.err == nil {
+		s.err = fmt.Errorf("no URL and no distributor")
+	}
+	return s
+}
+
+// renderWitnessStatuses draws a table of the witnesses' views of the log,
+// with each witness's lag behind the log checkpoint cp. Views cosigned more
+// than stale ago are highlighted.
+func renderWitnessStatuses(statuses []witnessStatus, cp *model.Checkpoint, now time.Time, stale time.Duration) string {
+	staleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171"))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, " %-32s %-12s %-10s %-22s %-8s %s\n", "Witness", "Size", "Lag", "Cosigned", "Age", "Source")
+	for _, s := range statuses {
+		if s.err != nil {
+			fmt.Fprintf(&sb, " %-32s %v\n", s.name, s.err)
+			continue
+		}
+		lag := "-"
+		if cp != nil && cp.Size >= s.size {
+			lag = strconv.FormatUint(cp.Size-s.size, 10)
+		}
+		ts, age := "-", "-"
+		if !s.timestamp.IsZero() {
+			ts = s.timestamp.UTC().Format(time.DateTime)
+			age = formatAge(now.Sub(s.timestamp))
+		}
+		line := fmt.Sprintf(" %-32s %-12d %-10s %-22s %-8s %s", s.name, s.size, lag, ts, age, s.source)
+		if !s.timestamp.IsZero() && now.Sub(s.timestamp) > stale {
+			line = staleStyle.Render(line)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
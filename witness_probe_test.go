@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mhutchinson/woodpecker/model"
+	"github.com/transparency-dev/formats/log"
+	tnote "github.com/transparency-dev/formats/note"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// fakeWitness serves the tlog-witness add-checkpoint endpoint for a witness
+// which has seen the log at the given size. It never updates its state.
+func fakeWitness(t *testing.T, w testWitness, size uint64) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/add-checkpoint" {
+			http.NotFound(rw, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("ReadAll: %v", err)
+			return
+		}
+		header, signed, ok := bytes.Cut(body, []byte("\n\n"))
+		var old uint64
+		if _, err := fmt.Sscanf(string(header), "old %d", &old); !ok || err != nil {
+			http.Error(rw, "bad request", http.StatusBadRequest)
+			return
+		}
+		if old != size {
+			rw.Header().Set("Content-Type", "text/x.tlog.size")
+			rw.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(rw, "%d\n", size)
+			return
+		}
+		// The witness would verify the log's signature here.
+		i := bytes.LastIndex(signed, []byte("\n\n"))
+		if i < 0 {
+			http.Error(rw, "bad note", http.StatusBadRequest)
+			return
+		}
+		text := string(signed[:i+1])
+		cosigned, err := note.Sign(&note.Note{Text: text}, w.signer)
+		if err != nil {
+			t.Errorf("Sign: %v", err)
+			return
+		}
+		_, sigs, _ := bytes.Cut(cosigned, []byte("\n\n"))
+		_, _ = rw.Write(sigs)
+	}))
+}
+
+func TestProbeWitness(t *testing.T) {
+	const origin = "example.com/log"
+	logSKey, logVKey, err := note.GenerateKey(rand.Reader, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logSigner, err := note.NewSigner(logSKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logVerifier, err := note.NewVerifier(logVKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newTestWitness(t, "w")
+	v, err := tnote.NewVerifierForCosignatureV1(w.vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := signCheckpoint(t, log.Checkpoint{Origin: origin, Size: 20, Hash: make([]byte, 32)}, logSigner)
+	c, _, n, err := log.ParseCheckpoint(raw, origin, logVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := &model.Checkpoint{Checkpoint: c, Note: n, Raw: raw}
+
+	t.Run("up to date", func(t *testing.T) {
+		s := fakeWitness(t, w, 20)
+		defer s.Close()
+		before := time.Now().Add(-time.Second)
+		got := probeWitness(t.Context(), s.URL, v, cp)
+		if got.err != nil {
+			t.Fatalf("probeWitness: %v", got.err)
+		}
+		if got.size != 20 {
+			t.Errorf("got size %d, want 20", got.size)
+		}
+		if got.timestamp.Before(before) {
+			t.Errorf("got timestamp %v, want a fresh cosignature", got.timestamp)
+		}
+	})
+
+	t.Run("behind", func(t *testing.T) {
+		s := fakeWitness(t, w, 12)
+		defer s.Close()
+		got := probeWitness(t.Context(), s.URL+"/", v, cp)
+		if got.err != nil {
+			t.Fatalf("probeWitness: %v", got.err)
+		}
+		if got.size != 12 {
+			t.Errorf("got size %d, want 12", got.size)
+		}
+		if !got.timestamp.IsZero() {
+			t.Errorf("got timestamp %v, want none from a conflict", got.timestamp)
+		}
+		if out := renderWitnessStatuses([]witnessStatus{got}, cp, time.Now(), time.Hour); !strings.Contains(out, "8") {
+			t.Errorf("expected a lag of 8 in:\n%s", out)
+		}
+	})
+
+	t.Run("unknown log", func(t *testing.T) {
+		s := httptest.NewServer(http.NotFoundHandler())
+		defer s.Close()
+		if got := probeWitness(t.Context(), s.URL, v, cp); got.err == nil {
+			t.Error("expected an error for a witness which doesn't know the log")
+		}
+	})
+}
+
+func TestWitnessStatusFromDistributor(t *testing.T) {
+	const origin = "example.com/log"
+	logSKey, logVKey, err := note.GenerateKey(rand.Reader, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logSigner, err := note.NewSigner(logSKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logVerifier, err := note.NewVerifier(logVKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newTestWitness(t, "w")
+	v, err := tnote.NewVerifierForCosignatureV1(w.vkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &policyLogClient{origin: origin, verifier: logVerifier}
+	dist := &mockDistributor{byWitness: map[string][]byte{
+		"w": signCheckpoint(t, log.Checkpoint{Origin: origin, Size: 15, Hash: make([]byte, 32)}, logSigner, w),
+	}}
+	m := NewModel([]string{origin}, map[string]logClient{origin: client}, mockDistributors(dist), []note.Verifier{v}, origin, modelOptions{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m.checkpoint = &model.Checkpoint{Checkpoint: &log.Checkpoint{Origin: origin, Size: 20}}
+
+	sendKey(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	if got := len(m.witnessStatuses); got != 1 {
+		t.Fatalf("got %d witness statuses, want 1", got)
+	}
+	s := m.witnessStatuses[0]
+	if s.err != nil || s.size != 15 || s.source != "mock" || s.timestamp.IsZero() {
+		t.Errorf("got status %+v, want size 15 from mock with a timestamp", s)
+	}
+	view := m.View()
+	if !strings.Contains(view, "Witness views of the log") {
+		t.Errorf("witnesses view does not list the witness views:\n%s", view)
+	}
+}